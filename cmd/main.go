@@ -2,107 +2,597 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/redis/go-redis/v9"
+	"github.com/sony/gobreaker/v2"
 
+	"github.com/olezhek28/docker-compose-tutorial/inernal/api"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/buildinfo"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/config"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/metrics"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/middleware"
 	"github.com/olezhek28/docker-compose-tutorial/inernal/migrator"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/seed"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/tracing"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/webhook"
+	"github.com/olezhek28/docker-compose-tutorial/migrations"
 )
 
-// User — структура для парсинга JSON-запроса
-type User struct {
-	Username string `json:"username"` // имя пользователя
-	Email    string `json:"email"`    // email пользователя
-}
+// serviceName — имя сервиса в трейсах
+const serviceName = "docker-compose-tutorial"
+
+// redisTimeout — предел на соединение и каждую операцию с Redis. Кэш лишь ускоряет чтение, поэтому
+// недоступный Redis должен быстро давать промах, а не съедать дедлайн запроса
+const redisTimeout = 200 * time.Millisecond
 
-// Глобальная переменная для пула соединений с базой данных
-var db *pgxpool.Pool
+// Подкоманды бинарника
+const (
+	commandServe   = "serve"
+	commandMigrate = "migrate"
+)
 
 func main() {
-	ctx := context.Background()
+	os.Exit(run(os.Args[1:], map[string]func(args []string){
+		commandServe:   serve,
+		commandMigrate: migrate,
+	}))
+}
 
-	// Строка подключения к Postgres
-	dbURI := os.Getenv("DB_URI")
+// run — выбирает подкоманду по первому аргументу и передаёт ей остальные. Возвращает код завершения:
+// 2 для неизвестной команды, как у flag при ошибке разбора
+func run(args []string, commands map[string]func(args []string)) int {
+	command, rest := splitCommand(args)
 
-	// Инициализируем пул соединений к базе данных Postgres
-	var err error
-	db, err = pgxpool.New(ctx, dbURI)
+	switch command {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return 0
+	}
+
+	handler, ok := commands[command]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Неизвестная команда %q\n\n", command)
+		usage()
+		return 2
+	}
+	handler(rest)
+
+	return 0
+}
+
+// splitCommand — отделяет подкоманду от её аргументов. Без подкоманды, в том числе когда первым идёт флаг,
+// запускается сервер: так прежние команды запуска вроде ./server -addr :9090 продолжают работать
+func splitCommand(args []string) (string, []string) {
+	if len(args) == 0 {
+		return commandServe, nil
+	}
+	if args[0] != "-h" && args[0] != "-help" && args[0] != "--help" && len(args[0]) > 0 && args[0][0] == '-' {
+		return commandServe, args
+	}
+
+	return args[0], args[1:]
+}
+
+// usage — печатает список подкоманд
+func usage() {
+	fmt.Fprintf(os.Stderr, `Использование: %[1]s <команда> [флаги]
+
+Команды:
+  serve                  запустить HTTP-сервер (по умолчанию); -skip-migrations — без применения миграций
+  migrate up|down|status применить миграции, откатить последнюю или показать их состояние и выйти
+
+Флаги команды: %[1]s <команда> -h
+`, serviceName)
+}
+
+// setup — читает конфигурацию из аргументов подкоманды и окружения и настраивает логирование.
+// На -h печатает справку и завершает процесс без ошибки
+func setup(args []string) *config.Config {
+	// Читаем и проверяем конфигурацию до того, как что-либо запускать
+	cfg, err := config.LoadConfig(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		log.Fatalf("Ошибка подключения к базе данных: %v", err)
+		fatal("Ошибка конфигурации", err)
 	}
-	// Закрываем пул соединений при завершении работы приложения
-	defer db.Close()
 
-	// Выставляем таймаут для проверки подключения к базе
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+	// Настраиваем структурированное логирование в формате JSON с уровнем из конфигурации
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.LogLevel})))
+	if cfg.EnvFile != "" {
+		slog.Info("Загружены переменные из файла", "path", cfg.EnvFile)
+	}
+
+	return cfg
+}
+
+// serve — подкоманда serve: применяет миграции, если их не отключили, и обслуживает HTTP-запросы до сигнала завершения
+func serve(args []string) {
+	ctx := context.Background()
+	cfg := setup(args)
 
-	// Проверяем, что соединение с базой установлено
-	err = db.Ping(ctx)
+	// Трассировка включается, только если задан адрес коллектора; иначе спаны не создаются
+	shutdownTracing, err := tracing.Setup(ctx, cfg.OTLPEndpoint, serviceName)
 	if err != nil {
-		log.Fatalf("База данных недоступна: %v", err)
+		fatal("Ошибка настройки трассировки", err)
+	}
+
+	// Инициализируем пул соединений к базе данных Postgres
+	db := connectDB(ctx, cfg)
+	// Закрываем пул соединений при завершении работы приложения.
+	// Defer срабатывает после остановки HTTP-сервера, поэтому активные запросы успеют дописать в базу
+	defer db.Close()
+
+	// Миграции можно вынести в отдельную задачу migrate up, например в init-контейнер.
+	// Мигратор нужен и в этом случае: readiness-проба по нему проверяет, что схема актуальна
+	migratorRunner := newMigrator(db, cfg)
+	if cfg.SkipMigrations {
+		slog.Info("Применение миграций при запуске отключено")
+	} else {
+		migrateUp(migratorRunner)
+	}
+
+	var users repository.UserRepository = repository.NewPostgresUserRepository(db, repository.RetryPolicy{
+		Attempts:  cfg.DBRetryAttempts,
+		BaseDelay: cfg.DBRetryDelay,
+	})
+	// Выключатель стоит снаружи повторов: серия запросов, исчерпавших попытки, размыкает его
+	if cfg.BreakerFailures > 0 {
+		users = repository.NewBreakerUserRepository(users, repository.BreakerOptions{
+			Failures: uint32(cfg.BreakerFailures),
+			Cooldown: cfg.BreakerCooldown,
+			OnStateChange: func(state gobreaker.State) {
+				metrics.SetCircuitBreakerState(float64(state))
+			},
+		})
+	}
+	// Кэш стоит снаружи выключателя: попадания в кэш не маскируют сбои базы и обслуживаются даже при её отказе
+	if cfg.CacheEnabled {
+		users = repository.NewCachedUserRepository(users, newUserCache(cfg))
+		slog.Info("Кэш пользователей включён", "backend", cfg.CacheBackend, "ttl", cfg.CacheTTL.String())
+	}
+
+	// Демонстрационные данные добавляются только по явному запросу, чтобы не попасть в рабочую базу
+	if cfg.Seed {
+		seedCtx, cancelSeed := context.WithTimeout(ctx, cfg.DBOpTimeout)
+		created, err := seed.Seed(seedCtx, users, cfg.BcryptCost)
+		cancelSeed()
+		if err != nil {
+			fatal("Ошибка заполнения демонстрационными данными", err)
+		}
+		slog.Warn("Добавлены демонстрационные пользователи", "created", created)
+	}
+
+	// Обработчики работают с пользователями через репозиторий, а не напрямую с пулом
+	// Уведомления о новых пользователях отправляются, только если задан адрес получателя
+	var events api.UserEvents
+	var notifier *webhook.Notifier
+	if cfg.WebhookURL != "" {
+		notifier = webhook.NewNotifier(webhook.Options{
+			URL:       cfg.WebhookURL,
+			Attempts:  cfg.WebhookAttempts,
+			Timeout:   cfg.WebhookTimeout,
+			BaseDelay: cfg.WebhookRetryDelay,
+		})
+		events = notifier
+		slog.Info("Вебхуки о новых пользователях включены", "attempts", cfg.WebhookAttempts)
+	}
+
+	// Список доменов читаем один раз при старте: проверка при каждой регистрации идёт по множеству в памяти
+	var disposableDomains api.DomainBlocklist
+	if cfg.DisposableDomainsFile != "" {
+		disposableDomains, err = api.LoadDomainBlocklist(cfg.DisposableDomainsFile)
+		if err != nil {
+			fatal("Ошибка загрузки списка доменов одноразовой почты", err)
+		}
+		slog.Info("Регистрация с одноразовой почты запрещена", "domains", len(disposableDomains))
+	}
+
+	apiHandler := api.NewHandler(users, repository.NewPostgresIdempotencyStore(db), events, db, migratorRunner, api.Options{
+		ReadinessTimeout:    cfg.ReadinessTimeout,
+		DBOpTimeout:         cfg.DBOpTimeout,
+		MaxBodyBytes:        cfg.MaxBodyBytes,
+		BcryptCost:          cfg.BcryptCost,
+		LegacyRoutes:        cfg.LegacyRoutes,
+		IdempotencyTTL:      cfg.IdempotencyTTL,
+		AdminToken:          cfg.AdminToken,
+		AdminMigrateEnabled: cfg.AdminMigrateEnabled,
+		DisposableDomains:   disposableDomains,
+	})
+
+	mux := http.NewServeMux()
+	apiHandler.Register(mux)
+	// Метрики для Prometheus
+	mux.Handle("GET /metrics", metrics.Handler())
+	metrics.RegisterPoolStats(db)
+	// Отладочные эндпоинты раскрывают внутреннее состояние, поэтому включаются только явно
+	if cfg.Debug {
+		api.RegisterDebug(mux, db)
+		slog.Warn("Включены отладочные эндпоинты /debug/*")
+	}
+
+	// Контекст отменяется при получении SIGINT или SIGTERM
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Собираем цепочку общих middleware, чтобы новые эндпоинты получали их автоматически
+	// Неизвестные маршруты и методы отвечают в едином JSON-формате ошибки
+	handler := api.WithJSONFallback(mux)
+	// Общий дедлайн запроса; поток событий живёт, пока клиент не отключится, а миграции — сколько потребуется,
+	// поэтому на них он не распространяется
+	if cfg.RequestTimeout > 0 {
+		timeoutMiddleware := middleware.Timeout(middleware.TimeoutOptions{
+			Timeout:     cfg.RequestTimeout,
+			ExemptPaths: []string{api.APIPrefix + "/users/stream", "/users/stream", api.AdminMigratePath},
+		})
+		handler = timeoutMiddleware(handler)
+	}
+	// Аутентификация стоит внутри ограничителя частоты, чтобы подбор токенов тоже упирался в лимит
+	// Пробы оркестратора, сбор метрик и сведения о сборке работают без аутентификации
+	publicPaths := []string{"/healthz", "/readyz", "/metrics", "/version"}
+	switch {
+	case cfg.JWTSecret != "":
+		authMiddleware := middleware.JWTAuth(middleware.AuthOptions{
+			Secret:      []byte(cfg.JWTSecret),
+			PublicPaths: publicPaths,
+		})
+		handler = authMiddleware(handler)
+	case cfg.BasicAuthEnabled():
+		authMiddleware := middleware.BasicAuth(middleware.BasicAuthOptions{
+			User:        cfg.BasicAuthUser,
+			Password:    cfg.BasicAuthPass,
+			PublicPaths: publicPaths,
+		})
+		handler = authMiddleware(handler)
+		slog.Info("Включена Basic-аутентификация", "user", cfg.BasicAuthUser)
+	case len(cfg.APIKeys) > 0:
+		authMiddleware := middleware.APIKeyAuth(middleware.APIKeyOptions{
+			Keys:        cfg.APIKeys,
+			PublicPaths: publicPaths,
+		})
+		handler = authMiddleware(handler)
+		slog.Info("Включена аутентификация по API-ключам", "keys", len(cfg.APIKeys))
+	default:
+		slog.Warn("Ни JWT_SECRET, ни BASIC_AUTH_USER, ни API_KEYS не заданы, аутентификация запросов выключена")
+	}
+	if cfg.RateLimitRPS > 0 {
+		rateLimiter := middleware.NewRateLimiter(ctx, middleware.RateLimitOptions{
+			RPS:        cfg.RateLimitRPS,
+			Burst:      cfg.RateLimitBurst,
+			TrustProxy: cfg.TrustProxy,
+		})
+		handler = rateLimiter.Middleware(handler)
+	}
+
+	corsMiddleware := middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins: cfg.CORSAllowedOrigins,
+		AllowedMethods: cfg.CORSAllowedMethods,
+		AllowedHeaders: cfg.CORSAllowedHeaders,
+	})
+
+	// CORS проверяем до ограничения частоты, чтобы preflight-запросы браузера не расходовали лимит
+	handler = corsMiddleware(handler)
+	// Паники перехватываем внутри метрик, чтобы такие запросы учитывались как ответы 500
+	handler = middleware.Recover(handler)
+	// Сжатие стоит снаружи Recover, чтобы ответ 500 после паники тоже прошёл через gzip-поток
+	handler = middleware.Gzip(handler)
+	handler = metrics.Middleware(handler)
+	// Журнал запросов стоит внутри RequestID, чтобы каждая запись содержала идентификатор запроса
+	handler = middleware.AccessLog(handler)
+	handler = middleware.RequestID(handler)
+	// Трассировку подключаем всегда: даже без экспорта она принимает traceparent от вызывающего сервиса,
+	// и логи запроса ссылаются на его trace_id
+	handler = tracing.Middleware(handler)
+
+	// Таймауты не дают медленным или зависшим клиентам бесконечно занимать соединения
+	server := &http.Server{
+		Addr:              cfg.HTTPAddr,
+		Handler:           handler,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+	}
+
+	// Запускаем HTTP-сервер в отдельной горутине, чтобы main мог дождаться сигнала
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Сервер запущен",
+			"addr", server.Addr,
+			"version", buildinfo.Version,
+			"commit", buildinfo.Commit,
+			"tls", cfg.TLSEnabled(),
+			"read_timeout", server.ReadTimeout.String(),
+			"read_header_timeout", server.ReadHeaderTimeout.String(),
+			"write_timeout", server.WriteTimeout.String(),
+			"idle_timeout", server.IdleTimeout.String(),
+		)
+
+		// HTTPS включается, только если заданы сертификат и ключ; иначе обычный HTTP.
+		// Остановка через server.Shutdown работает одинаково в обоих случаях
+		var err error
+		if cfg.TLSEnabled() {
+			err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	// Профилировщик обслуживается отдельным сервером, чтобы не попасть на публичный порт.
+	// WriteTimeout не задаём: снятие CPU-профиля длится десятки секунд
+	var pprofServer *http.Server
+	if cfg.PprofEnabled {
+		pprofServer = &http.Server{
+			Addr:              cfg.PprofAddr,
+			Handler:           api.PprofHandler(),
+			ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		}
+
+		go func() {
+			slog.Warn("Запущен профилировщик pprof", "addr", pprofServer.Addr)
+			if err := pprofServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- fmt.Errorf("сервер профилировщика: %w", err)
+			}
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
+		fatal("Ошибка сервера", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("Получен сигнал завершения, останавливаем сервер", "timeout", cfg.ShutdownTimeout.String())
+
+	// Даём активным запросам время на завершение, новые соединения уже не принимаются
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Ошибка при остановке сервера", "error", err)
+	}
+	if pprofServer != nil {
+		if err := pprofServer.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Ошибка при остановке профилировщика", "error", err)
+		}
+	}
+
+	slog.Info("Сервер остановлен")
+
+	// Запросы завершены, новых событий не будет; дожидаемся доставки уже поставленных
+	if notifier != nil {
+		if err := notifier.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Ошибка при остановке вебхуков", "error", err)
+		}
 	}
 
-	// Инициализируем мигратор
-	migrationsDir := os.Getenv("MIGRATIONS_DIR")
-	migratorRunner := migrator.NewMigrator(stdlib.OpenDB(*db.Config().ConnConfig), migrationsDir)
+	// Отправляем спаны, накопленные к моменту остановки
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelTracing()
 
-	err = migratorRunner.Up()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Error("Ошибка при остановке трассировки", "error", err)
+	}
+}
+
+// connectDB — создаёт пул соединений и дожидается доступности базы
+func connectDB(ctx context.Context, cfg *config.Config) *pgxpool.Pool {
+	db, err := newPool(ctx, cfg)
 	if err != nil {
-		log.Fatalf("Ошибка миграции базы данных: %v", err)
+		fatal("Ошибка подключения к базе данных", err)
+	}
+
+	// Проверяем, что соединение с базой установлено. В Docker Compose приложение может стартовать
+	// раньше, чем Postgres начнёт принимать соединения, поэтому даём базе несколько попыток
+	err = pingWithRetry(ctx, db, cfg.DBConnectAttempts, cfg.DBConnectDelay, cfg.StartupPingTimeout)
+	if err != nil {
+		db.Close()
+		fatal("База данных недоступна", err)
+	}
+
+	if cfg.DBSchema != "" {
+		if err := ensureSchema(ctx, db, cfg.DBSchema); err != nil {
+			db.Close()
+			fatal("Ошибка подготовки схемы базы данных", err)
+		}
+	}
+
+	return db
+}
+
+// setSearchPath — хук AfterConnect, направляющий запросы нового соединения в схему schema.
+// Только она, без public: иначе таблица, которой ещё нет в schema, нашлась бы в public
+func setSearchPath(schema string) func(context.Context, *pgx.Conn) error {
+	query := "SET search_path TO " + pgx.Identifier{schema}.Sanitize()
+
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		if _, err := conn.Exec(ctx, query); err != nil {
+			return fmt.Errorf("не удалось установить search_path: %w", err)
+		}
+
+		return nil
 	}
+}
 
-	// Регистрируем обработчик HTTP-запросов на эндпоинте /users
-	http.HandleFunc("/users", createUserHandler)
+// ensureSchema — создаёт схему schema, если её ещё нет. Существование проверяем заранее:
+// CREATE SCHEMA IF NOT EXISTS требует права CREATE на базу, даже когда схема уже есть
+func ensureSchema(ctx context.Context, db *pgxpool.Pool, schema string) error {
+	var exists bool
+	err := db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)`, schema).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("не удалось проверить схему %s: %w", schema, err)
+	}
+	if exists {
+		return nil
+	}
 
-	fmt.Println("Сервер запущен на :8080")
-	// Запускаем HTTP-сервер на порту 8080
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatalf("Ошибка сервера: %v", err)
+	if _, err := db.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{schema}.Sanitize()); err != nil {
+		return fmt.Errorf("не удалось создать схему %s: %w", schema, err)
 	}
+	slog.Info("Создана схема базы данных", "schema", schema)
+
+	return nil
 }
 
-// createUserHandler — обработчик POST-запросов для создания нового пользователя
-func createUserHandler(w http.ResponseWriter, r *http.Request) {
-	// Проверяем, что метод запроса — POST
-	if r.Method != http.MethodPost {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
-		return
+// newMigrator — создаёт мигратор: миграции из директории, если она задана, иначе встроенные в бинарник.
+// Соединения мигратора открываются в той же схеме DB_SCHEMA, что и пул, поэтому таблица версий
+// и таблицы миграций создаются в ней
+func newMigrator(db *pgxpool.Pool, cfg *config.Config) *migrator.Migrator {
+	var opts []stdlib.OptionOpenDB
+	if cfg.DBSchema != "" {
+		opts = append(opts, stdlib.OptionAfterConnect(setSearchPath(cfg.DBSchema)))
 	}
+	sqlDB := stdlib.OpenDB(*db.Config().ConnConfig, opts...)
 
-	var user User
-	// Парсим JSON-тело запроса в структуру User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Некорректный JSON", http.StatusBadRequest)
-		return
+	var migratorRunner *migrator.Migrator
+	if cfg.MigrationsDir != "" {
+		migratorRunner = migrator.NewMigrator(sqlDB, cfg.MigrationsDir)
+	} else {
+		migratorRunner = migrator.NewMigratorFS(sqlDB, migrations.FS)
 	}
+	migratorRunner.SetRecorder(metrics.MigrationRecorder{})
+
+	return migratorRunner
+}
 
-	// Проверяем, что оба обязательных поля присутствуют
-	if user.Username == "" || user.Email == "" {
-		http.Error(w, "Поля username и email обязательны", http.StatusBadRequest)
-		return
+// newUserCache — создаёт кэш пользователей выбранного в CACHE_BACKEND типа.
+// Соединение с Redis устанавливается лениво: недоступный при старте Redis не мешает запуску,
+// а запросы до его появления идут в базу
+func newUserCache(cfg *config.Config) repository.UserCache {
+	if cfg.CacheBackend == config.CacheBackendRedis {
+		client := redis.NewClient(&redis.Options{
+			Addr:         cfg.RedisAddr,
+			Password:     cfg.RedisPassword,
+			DialTimeout:  redisTimeout,
+			ReadTimeout:  redisTimeout,
+			WriteTimeout: redisTimeout,
+			PoolTimeout:  redisTimeout,
+			// Повтор при промахе кэша не нужен: запрос и так уйдёт в базу. -1 отключает повторы, 0 — значение по умолчанию
+			MaxRetries:            -1,
+			ContextTimeoutEnabled: true,
+		})
+		return repository.NewRedisCache(client, cfg.CacheTTL)
 	}
 
-	// Контекст с таймаутом для выполнения запроса к базе
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
+	return repository.NewLRUCache(repository.CacheOptions{
+		Size: cfg.CacheSize,
+		TTL:  cfg.CacheTTL,
+	})
+}
+
+// queryExecModes — режимы pgx по значениям PGX_STATEMENT_CACHE_MODE; сами значения проверяет конфигурация
+var queryExecModes = map[string]pgx.QueryExecMode{
+	config.StatementCacheModeStatement: pgx.QueryExecModeCacheStatement,
+	config.StatementCacheModeDescribe:  pgx.QueryExecModeCacheDescribe,
+	config.StatementCacheModeDescExec:  pgx.QueryExecModeDescribeExec,
+	config.StatementCacheModeExec:      pgx.QueryExecModeExec,
+	config.StatementCacheModeSimple:    pgx.QueryExecModeSimpleProtocol,
+}
 
-	// SQL-запрос на вставку данных в таблицу users
-	query := `INSERT INTO users (username, email) VALUES ($1, $2)`
-	_, err := db.Exec(ctx, query, user.Username, user.Email)
+// newPool — создаёт пул соединений, переопределяя размер пула и время жизни соединений из конфигурации
+func newPool(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DBURI)
 	if err != nil {
-		log.Printf("Ошибка вставки в базу: %v", err)
-		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("некорректная строка подключения: %w", err)
+	}
+
+	poolConfig.MaxConns = cfg.DBMaxConns
+	poolConfig.MinConns = cfg.DBMinConns
+	poolConfig.MaxConnLifetime = cfg.DBMaxConnLifetime
+	poolConfig.ConnConfig.DefaultQueryExecMode = queryExecModes[cfg.DBStatementCacheMode]
+	if cfg.DBSchema != "" {
+		poolConfig.AfterConnect = setSearchPath(cfg.DBSchema)
+	}
+	var tracers []pgx.QueryTracer
+	// Спаны на запросы к базе создаём, только когда трассировка включена
+	if cfg.OTLPEndpoint != "" {
+		tracers = append(tracers, tracing.NewQueryTracer())
+	}
+	// Медленные запросы логируем, только когда задан порог
+	if cfg.SlowQueryThreshold > 0 {
+		tracers = append(tracers, repository.NewSlowQueryLogger(cfg.SlowQueryThreshold))
 	}
+	// pgx принимает один трейсер, поэтому несколько объединяем
+	switch len(tracers) {
+	case 0:
+	case 1:
+		poolConfig.ConnConfig.Tracer = tracers[0]
+	default:
+		poolConfig.ConnConfig.Tracer = multitracer.New(tracers...)
+	}
+
+	slog.Info("Настройки пула соединений",
+		"max_conns", poolConfig.MaxConns,
+		"min_conns", poolConfig.MinConns,
+		"max_conn_lifetime", poolConfig.MaxConnLifetime.String(),
+		"statement_cache_mode", cfg.DBStatementCacheMode,
+		"schema", cfg.DBSchema,
+		"slow_query_threshold", cfg.SlowQueryThreshold.String(),
+	)
+
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
+
+// pinger — база, доступность которой проверяет pingWithRetry; *pgxpool.Pool подходит как есть
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// pingWithRetry — проверяет доступность базы до attempts раз, удваивая паузу между попытками,
+// начиная с baseDelay; каждая проверка ограничена timeout. Возвращает ошибку последней попытки, если все они неудачны
+func pingWithRetry(ctx context.Context, db pinger, attempts int, baseDelay, timeout time.Duration) error {
+	delay := baseDelay
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		// Выставляем таймаут для каждой проверки подключения к базе
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = db.Ping(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		slog.Warn("База данных недоступна, повторяем попытку",
+			"attempt", attempt,
+			"max_attempts", attempts,
+			"retry_in", delay.String(),
+			"error", err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	return fmt.Errorf("база недоступна после %d попыток: %w", attempts, err)
+}
 
-	// Возвращаем успешный ответ клиенту
-	w.WriteHeader(http.StatusCreated)
-	fmt.Fprintln(w, "Пользователь успешно создан")
+// fatal — записывает ошибку запуска в лог как структурированную запись и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/migrator"
+)
+
+// Действия подкоманды migrate
+const (
+	migrateActionUp     = "up"
+	migrateActionDown   = "down"
+	migrateActionStatus = "status"
+)
+
+// migrate — подкоманда migrate up|down|status: выполняет действие с миграциями и завершает процесс.
+// Позволяет применять миграции отдельной задачей, не запуская сервер
+func migrate(args []string) {
+	action, args, ok := parseMigrateAction(args)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Использование: %s migrate up|down|status [флаги]\n", serviceName)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	cfg := setup(args)
+
+	db := connectDB(ctx, cfg)
+	defer db.Close()
+
+	migratorRunner := newMigrator(db, cfg)
+
+	switch action {
+	case migrateActionUp:
+		migrateUp(migratorRunner)
+	case migrateActionDown:
+		migrateDown(migratorRunner)
+	case migrateActionStatus:
+		migrateStatus(migratorRunner)
+	}
+}
+
+// parseMigrateAction — отделяет действие migrate от флагов. false — действие не задано или неизвестно
+func parseMigrateAction(args []string) (string, []string, bool) {
+	if len(args) == 0 {
+		return "", nil, false
+	}
+
+	switch args[0] {
+	case migrateActionUp, migrateActionDown, migrateActionStatus:
+		return args[0], args[1:], true
+	default:
+		return "", nil, false
+	}
+}
+
+// migrateUp — применяет ожидающие миграции, перед этим перечисляя их в логе
+func migrateUp(migratorRunner *migrator.Migrator) {
+	// Перед применением показываем, какие миграции будут выполнены
+	pending, err := migratorRunner.Pending()
+	if err != nil {
+		fatal("Ошибка получения списка ожидающих миграций", err)
+	}
+	for _, migration := range pending {
+		slog.Info("Ожидает применения миграция", "version", migration.Version, "name", migration.Name)
+	}
+
+	err = migratorRunner.Up()
+	if err != nil {
+		fatal("Ошибка миграции базы данных", err)
+	}
+
+	schemaVersion, err := migratorRunner.Version()
+	if err != nil {
+		fatal("Ошибка получения версии схемы", err)
+	}
+	slog.Info("Миграции применены", "schema_version", schemaVersion)
+}
+
+// migrateDown — откатывает только последнюю применённую миграцию, как goose down.
+// Полный откат слишком разрушителен, чтобы выполняться одной командой
+func migrateDown(migratorRunner *migrator.Migrator) {
+	err := migratorRunner.DownOne()
+	if errors.Is(err, migrator.ErrNoAppliedMigrations) {
+		slog.Warn("Нет применённых миграций для отката")
+		return
+	}
+	if err != nil {
+		fatal("Ошибка отката миграции", err)
+	}
+
+	schemaVersion, err := migratorRunner.Version()
+	if err != nil {
+		fatal("Ошибка получения версии схемы", err)
+	}
+	slog.Info("Последняя миграция откачена", "schema_version", schemaVersion)
+}
+
+// migrateStatus — печатает таблицу миграций с отметкой, применены ли они
+func migrateStatus(migratorRunner *migrator.Migrator) {
+	statuses, err := migratorRunner.Status()
+	if err != nil {
+		fatal("Ошибка получения состояния миграций", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "ожидает"
+		if status.Applied {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	if err := tw.Flush(); err != nil {
+		fatal("Ошибка вывода состояния миграций", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/config"
+)
+
+// testDBURIEnv — переменная со строкой подключения к Postgres для тестов, которым нужна настоящая база.
+// Без неё такие тесты пропускаются
+const testDBURIEnv = "TEST_DB_URI"
+
+func TestSchemaPoolAndMigrations(t *testing.T) {
+	uri := os.Getenv(testDBURIEnv)
+	if uri == "" {
+		t.Skipf("%s не задана, тест с Postgres пропущен", testDBURIEnv)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg := &config.Config{
+		DBURI:                uri,
+		DBMaxConns:           2,
+		DBStatementCacheMode: config.StatementCacheModeDescribe,
+		DBSchema:             "test_" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+	}
+	db, err := newPool(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	// Схемы ещё нет: ensureSchema создаёт её, повторный вызов ничего не меняет
+	for range 2 {
+		if err := ensureSchema(ctx, db, cfg.DBSchema); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		_, _ = db.Exec(context.Background(), "DROP SCHEMA "+cfg.DBSchema+" CASCADE")
+	})
+
+	var current string
+	if err := db.QueryRow(ctx, "SELECT current_schema()").Scan(&current); err != nil {
+		t.Fatal(err)
+	}
+	if current != cfg.DBSchema {
+		t.Fatalf("current_schema() = %q, ожидалась %q", current, cfg.DBSchema)
+	}
+
+	if err := newMigrator(db, cfg).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Таблицы сервиса и таблица версий миграций созданы в заданной схеме
+	for _, table := range []string{"users", "goose_db_version"} {
+		var schema string
+		err := db.QueryRow(ctx,
+			`SELECT table_schema FROM information_schema.tables WHERE table_name = $1 AND table_schema = $2`,
+			table, cfg.DBSchema).Scan(&schema)
+		if err != nil {
+			t.Fatalf("таблица %s в схеме %s: %v", table, cfg.DBSchema, err)
+		}
+	}
+}
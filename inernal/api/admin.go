@@ -0,0 +1,42 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// AdminTokenHeader — заголовок с токеном администратора для разрушительных операций
+const AdminTokenHeader = "X-Admin-Token"
+
+// admin — пропускает к обработчику только запросы с верным токеном администратора в X-Admin-Token.
+// Проверяется вдобавок к общей аутентификации; без настроенного токена административные маршруты закрыты
+func (h *Handler) admin(next http.HandlerFunc) http.HandlerFunc {
+	want := sha256.Sum256([]byte(h.opts.AdminToken))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.opts.AdminToken == "" {
+			requestLogger(r).Warn("Административный маршрут вызван без настроенного ADMIN_TOKEN")
+			response.Error(w, http.StatusForbidden, response.CodeForbidden, "Административные операции отключены")
+			return
+		}
+
+		token := r.Header.Get(AdminTokenHeader)
+		if token == "" {
+			response.Error(w, http.StatusUnauthorized, response.CodeUnauthorized, "Требуется токен администратора в заголовке "+AdminTokenHeader)
+			return
+		}
+
+		// Сравниваем хеши фиксированной длины, чтобы время ответа не выдавало ни длину, ни содержимое токена
+		got := sha256.Sum256([]byte(token))
+		if subtle.ConstantTimeCompare(got[:], want[:]) != 1 {
+			requestLogger(r).Warn("Неверный токен администратора")
+			response.Error(w, http.StatusForbidden, response.CodeForbidden, "Неверный токен администратора")
+			return
+		}
+
+		next(w, r)
+	}
+}
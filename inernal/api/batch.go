@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// maxBatchSize — максимальное число пользователей в одном запросе POST /users/batch
+const maxBatchSize = 1000
+
+// batchItemError — ошибка проверки элемента пакета с его позицией в исходном массиве
+type batchItemError struct {
+	Index   int    `json:"index"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// createUsersBatchHandler — обработчик POST-запросов для создания пользователей пакетом.
+// Пакет сохраняется целиком в одной транзакции или не сохраняется вовсе
+func (h *Handler) createUsersBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var users []model.User
+	// Парсим JSON-массив пользователей
+	if !h.decodeJSON(w, r, &users) {
+		return
+	}
+
+	if len(users) == 0 {
+		response.Error(w, http.StatusBadRequest, response.CodeValidationFailed, "Пакет пользователей пуст")
+		return
+	}
+	if len(users) > maxBatchSize {
+		requestLogger(r).Warn("Слишком большой пакет пользователей", "size", len(users))
+		response.Error(w, http.StatusRequestEntityTooLarge, response.CodePayloadTooLarge,
+			fmt.Sprintf("В пакете не может быть больше %d пользователей", maxBatchSize))
+		return
+	}
+
+	// Проверяем все элементы и собираем ошибки сразу, чтобы клиент исправил пакет за один раз
+	var itemErrors []batchItemError
+	for i := range users {
+		if validationErr := validateNewUser(&users[i]); validationErr != nil {
+			for _, fieldErr := range validationErr.Fields {
+				itemErrors = append(itemErrors, batchItemError{Index: i, Field: fieldErr.Field, Message: fieldErr.Message})
+			}
+		}
+	}
+	if len(itemErrors) > 0 {
+		requestLogger(r).Warn("Некорректные данные в пакете пользователей", "invalid", len(itemErrors))
+		response.ErrorWithDetails(w, http.StatusBadRequest, response.CodeValidationFailed,
+			"Некорректные данные пользователей", itemErrors)
+		return
+	}
+
+	// Пакет не должен становиться обходом запрета одноразовой почты для одиночного создания
+	for i := range users {
+		if h.opts.DisposableDomains.blocked(users[i].Email) {
+			itemErrors = append(itemErrors, batchItemError{Index: i, Field: "email", Message: "Домен одноразовой почты"})
+		}
+	}
+	if len(itemErrors) > 0 {
+		requestLogger(r).Warn("В пакете есть адреса одноразовой почты", "blocked", len(itemErrors))
+		response.ErrorWithDetails(w, http.StatusUnprocessableEntity, response.CodeDisposableEmail,
+			"Одноразовые почтовые адреса запрещены", itemErrors)
+		return
+	}
+
+	// Хешируем пароли до начала транзакции, чтобы не держать её открытой во время вычислений
+	for i := range users {
+		if err := hashPassword(&users[i], h.opts.BcryptCost); err != nil {
+			requestLogger(r).Error("Ошибка хеширования пароля", "error", err)
+			response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Ошибка сервера")
+			return
+		}
+	}
+
+	// Хеширование заметно по времени: если клиент за это время отключился, вставку не выполняем
+	if clientGone(r) {
+		return
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	if err := h.users.CreateBatch(ctx, users); err != nil {
+		// Конфликт возможен и с существующими пользователями, и между элементами самого пакета
+		if writeConflict(w, r, err) {
+			return
+		}
+
+		writeDBError(w, r, "Ошибка пакетной вставки в базу", err)
+		return
+	}
+
+	requestLogger(r).Info("Пользователи созданы пакетом", "count", len(users))
+	h.userCreated(users...)
+
+	// Возвращаем созданных пользователей вместе с их идентификаторами
+	response.Data(w, http.StatusCreated, users, map[string]int{"count": len(users)})
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// batchBody — JSON-массив из n корректных пользователей с именами prefix_0, prefix_1, ...
+func batchBody(prefix string, n int) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"username":"%s_%d","email":"%s_%d@example.com","password":"secret-password"}`,
+			prefix, i, prefix, i)
+	}
+
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func TestCreateUsersBatch(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users/batch", batchBody("user", 3))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	var users []model.User
+	decodeData(t, rec, &users)
+	if len(users) != 3 {
+		t.Fatalf("создано %d пользователей, ожидалось 3", len(users))
+	}
+	for i, user := range users {
+		if user.ID == uuid.Nil || user.Username != fmt.Sprintf("user_%d", i) {
+			t.Fatalf("элемент %d: %+v", i, user)
+		}
+	}
+	if count := s.countUsers(t); count != 3 {
+		t.Fatalf("в хранилище %d пользователей, ожидалось 3", count)
+	}
+}
+
+func TestCreateUsersBatchInvalidItem(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	body := `[{"username":"alice","email":"alice@example.com","password":"secret-password"},` +
+		`{"username":"bob","email":"notanemail","password":"secret-password"}]`
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users/batch", body)
+	apiErr := expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+
+	var details []batchItemError
+	if err := json.Unmarshal(apiErr.Details, &details); err != nil {
+		t.Fatal(err)
+	}
+	if len(details) != 1 || details[0].Index != 1 || details[0].Field != "email" {
+		t.Fatalf("details = %+v, ожидалась ошибка email у элемента 1", details)
+	}
+	// Пакет сохраняется целиком или не сохраняется вовсе
+	if count := s.countUsers(t); count != 0 {
+		t.Fatalf("после ошибки сохранено %d пользователей", count)
+	}
+}
+
+func TestCreateUsersBatchConflictRollsBack(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	s.createUser(t, "existing", "user_2@example.com")
+
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users/batch", batchBody("user", 3))
+	expectError(t, rec, http.StatusConflict, response.CodeEmailTaken)
+	if count := s.countUsers(t); count != 1 {
+		t.Fatalf("после конфликта в хранилище %d пользователей, ожидался только существующий", count)
+	}
+}
+
+func TestCreateUsersBatchTooLarge(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users/batch", batchBody("user", maxBatchSize+1))
+	expectError(t, rec, http.StatusRequestEntityTooLarge, response.CodePayloadTooLarge)
+	if count := s.countUsers(t); count != 0 {
+		t.Fatalf("из слишком большого пакета сохранено %d пользователей", count)
+	}
+
+	rec = s.do(t, http.MethodPost, APIPrefix+"/users/batch", "[]")
+	expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+}
+
+func TestCreateUserObjectOrArray(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	// Объект — как раньше: в ответе один пользователь
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users",
+		` {"username":"alice","email":"alice@example.com","password":"secret-password"}`)
+	var single map[string]any
+	decodeData(t, rec, &single)
+	if rec.Code != http.StatusCreated || single["username"] != "alice" {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+
+	// Массив, в том числе после пробелов и переводов строки, создаётся пакетом и возвращается массивом
+	rec = s.do(t, http.MethodPost, APIPrefix+"/users", "\n\t "+batchBody("user", 2))
+	var users []model.User
+	decodeData(t, rec, &users)
+	if rec.Code != http.StatusCreated || len(users) != 2 || users[0].Username != "user_0" || users[1].Username != "user_1" {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	if count := s.countUsers(t); count != 3 {
+		t.Fatalf("в хранилище %d пользователей, ожидалось 3", count)
+	}
+}
+
+func TestCreateUserArrayValidatesEachItem(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	body := `[{"username":"bob","email":"bob@example.com","password":"secret-password"},` +
+		`{"username":"b","email":"bob2@example.com","password":"secret-password"}]`
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users", body)
+	apiErr := expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+
+	var details []batchItemError
+	if err := json.Unmarshal(apiErr.Details, &details); err != nil {
+		t.Fatal(err)
+	}
+	if len(details) != 1 || details[0].Index != 1 || details[0].Field != "username" {
+		t.Fatalf("details = %+v, ожидалась ошибка username у элемента 1", details)
+	}
+	if count := s.countUsers(t); count != 0 {
+		t.Fatalf("после ошибки сохранено %d пользователей", count)
+	}
+
+	// Тело из одних пробелов — некорректный JSON, а не пустой пакет
+	rec = s.do(t, http.MethodPost, APIPrefix+"/users", "   ")
+	expectError(t, rec, http.StatusBadRequest, response.CodeInvalidJSON)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+)
+
+// captureLevels — пишет лог до конца теста в буфер, начиная с уровня debug
+func captureLevels(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return &logs
+}
+
+// expectClientGone — проверяет, что отключение клиента не дало ни ответа, ни ошибки в логе, а только запись debug
+func expectClientGone(t *testing.T, rec *httptest.ResponseRecorder, logs *bytes.Buffer) {
+	t.Helper()
+
+	if rec.Body.Len() != 0 {
+		t.Fatalf("после отключения клиента записан ответ %d: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(logs.String(), "level=ERROR") {
+		t.Fatalf("отключение клиента залогировано как ошибка:\n%s", logs)
+	}
+	if !strings.Contains(logs.String(), "level=DEBUG msg=\"Клиент отключился") {
+		t.Fatalf("в логе нет записи об отключении клиента:\n%s", logs)
+	}
+}
+
+func TestCreateUserClientGone(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	logs := captureLevels(t)
+
+	// Клиент отключился до вставки: пользователь не создаётся
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	body := `{"username":"alice","email":"alice@example.com","password":"secret-password"}`
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, APIPrefix+"/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	expectClientGone(t, rec, logs)
+	if count := s.countUsers(t); count != 0 {
+		t.Fatalf("создано пользователей: %d, ожидалось 0", count)
+	}
+}
+
+// disconnectingUserRepository — хранилище, клиент которого отключается во время запроса к базе
+type disconnectingUserRepository struct {
+	*repository.MemoryUserRepository
+	disconnect context.CancelFunc
+}
+
+func (r disconnectingUserRepository) GetByID(ctx context.Context, _ uuid.UUID) (model.User, error) {
+	r.disconnect()
+	<-ctx.Done()
+
+	return model.User{}, ctx.Err()
+}
+
+func TestGetUserClientGoneDuringQuery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	users := disconnectingUserRepository{MemoryUserRepository: repository.NewMemoryUserRepository(), disconnect: cancel}
+	handler := NewHandler(users, nil, nil, nil, nil, testOptions())
+	mux := http.NewServeMux()
+	handler.Register(mux)
+	logs := captureLevels(t)
+
+	// Отмена запроса к базе из-за отключения клиента — не 500
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, APIPrefix+"/users/"+uuid.NewString(), nil)
+	rec := httptest.NewRecorder()
+	WithJSONFallback(mux).ServeHTTP(rec, req)
+
+	expectClientGone(t, rec, logs)
+}
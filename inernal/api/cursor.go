@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+)
+
+// errInvalidCursor — курсор повреждён или выдан для другого порядка сортировки
+var errInvalidCursor = errors.New("параметр cursor некорректен или не соответствует параметру sort")
+
+// cursorToken — содержимое непрозрачного курсора. Порядок сортировки хранится внутри,
+// чтобы курсор нельзя было применить к выборке с другим упорядочиванием
+type cursorToken struct {
+	Sort      string    `json:"s"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	Username  string    `json:"u,omitempty"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// encodeCursor — строит курсор, указывающий на позицию сразу после user
+func encodeCursor(sort repository.Sort, user model.User) string {
+	token := cursorToken{Sort: sortString(sort), ID: user.ID}
+	if sort.Field == repository.SortByUsername {
+		token.Username = user.Username
+	} else {
+		token.CreatedAt = user.CreatedAt
+	}
+
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor — разбирает курсор из ?cursor= и проверяет, что он выдан для того же порядка сортировки
+func decodeCursor(value string, sort repository.Sort) (*repository.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil || token.ID == uuid.Nil {
+		return nil, errInvalidCursor
+	}
+	if token.Sort != sortString(sort) {
+		return nil, errInvalidCursor
+	}
+
+	return &repository.Cursor{
+		CreatedAt: token.CreatedAt,
+		Username:  token.Username,
+		ID:        token.ID,
+	}, nil
+}
+
+// sortString — представление порядка сортировки в формате параметра ?sort=
+func sortString(sort repository.Sort) string {
+	if sort.Desc {
+		return "-" + string(sort.Field)
+	}
+
+	return string(sort.Field)
+}
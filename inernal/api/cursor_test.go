@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+func TestListUsersCursorPagination(t *testing.T) {
+	for _, sort := range []string{"-created_at", "created_at", "username", "-username"} {
+		t.Run(sort, func(t *testing.T) {
+			s := newTestServer(t, testOptions())
+			want := make(map[string]bool)
+			for i := range 7 {
+				user := s.createUser(t, fmt.Sprintf("user_%d", i), fmt.Sprintf("user_%d@example.com", i))
+				want[user.ID.String()] = true
+			}
+
+			seen := make(map[string]bool)
+			query := "?limit=3&sort=" + sort
+			for page := 1; ; page++ {
+				rec := s.do(t, http.MethodGet, APIPrefix+"/users"+query, "")
+				if rec.Code != http.StatusOK {
+					t.Fatalf("страница %d: статус %d, тело %s", page, rec.Code, rec.Body)
+				}
+				var users []model.User
+				decodeData(t, rec, &users)
+				for _, user := range users {
+					if seen[user.ID.String()] {
+						t.Fatalf("страница %d: пользователь %s повторился", page, user.Username)
+					}
+					seen[user.ID.String()] = true
+				}
+
+				// Между страницами появляются новые пользователи: уже выданные записи не сдвигаются
+				s.createUser(t, fmt.Sprintf("new_%d", page), fmt.Sprintf("new_%d@example.com", page))
+
+				cursor := rec.Header().Get("X-Next-Cursor")
+				if cursor == "" {
+					break
+				}
+				if page > 10 {
+					t.Fatal("курсор не заканчивается")
+				}
+				query = "?limit=3&sort=" + sort + "&cursor=" + cursor
+			}
+
+			// Все исходные пользователи получены ровно по одному разу, без пропусков
+			for id := range want {
+				if !seen[id] {
+					t.Fatalf("пользователь %s пропущен при листании", id)
+				}
+			}
+		})
+	}
+}
+
+func TestListUsersCursorLastPage(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	for i := range 2 {
+		s.createUser(t, fmt.Sprintf("user_%d", i), fmt.Sprintf("user_%d@example.com", i))
+	}
+
+	// Список исчерпан: курсора нет ни в заголовке, ни в meta
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users?limit=2", "")
+	if rec.Header().Get("X-Next-Cursor") != "" {
+		t.Fatalf("на последней странице выдан курсор %q", rec.Header().Get("X-Next-Cursor"))
+	}
+	var meta struct {
+		Meta map[string]any `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := meta.Meta["next_cursor"]; ok {
+		t.Fatalf("в meta последней страницы есть next_cursor: %v", meta.Meta)
+	}
+}
+
+func TestListUsersInvalidCursor(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	for i := range 3 {
+		s.createUser(t, fmt.Sprintf("user_%d", i), fmt.Sprintf("user_%d@example.com", i))
+	}
+	cursor := s.do(t, http.MethodGet, APIPrefix+"/users?limit=1", "").Header().Get("X-Next-Cursor")
+	if cursor == "" {
+		t.Fatal("курсор не выдан")
+	}
+
+	for _, query := range []string{
+		"?cursor=not-base64!",
+		"?cursor=e30",
+		// Курсор выдан для другой сортировки
+		"?sort=username&cursor=" + cursor,
+		// Курсор и offset взаимоисключающие
+		"?offset=1&cursor=" + cursor,
+	} {
+		rec := s.do(t, http.MethodGet, APIPrefix+"/users"+query, "")
+		expectError(t, rec, http.StatusBadRequest, response.CodeInvalidQuery)
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// PoolStatter — источник статистики пула соединений, например *pgxpool.Pool
+type PoolStatter interface {
+	Stat() *pgxpool.Stat
+}
+
+// poolStats — снимок состояния пула соединений для /debug/pool
+type poolStats struct {
+	TotalConns      int32  `json:"total_conns"`
+	AcquiredConns   int32  `json:"acquired_conns"`
+	IdleConns       int32  `json:"idle_conns"`
+	MaxConns        int32  `json:"max_conns"`
+	AcquireCount    int64  `json:"acquire_count"`
+	AcquireDuration string `json:"acquire_duration"`
+	EmptyAcquires   int64  `json:"empty_acquire_count"`
+	CanceledAcquire int64  `json:"canceled_acquire_count"`
+}
+
+// RegisterDebug регистрирует отладочные маршруты. Вызывается только при включённом режиме отладки,
+// поэтому по умолчанию эти маршруты отвечают 404
+func RegisterDebug(mux *http.ServeMux, pool PoolStatter) {
+	mux.HandleFunc("GET /debug/pool", poolStatsHandler(pool))
+}
+
+// poolStatsHandler — отдаёт текущую статистику пула соединений в формате JSON
+func poolStatsHandler(pool PoolStatter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stat := pool.Stat()
+
+		response.Data(w, http.StatusOK, poolStats{
+			TotalConns:      stat.TotalConns(),
+			AcquiredConns:   stat.AcquiredConns(),
+			IdleConns:       stat.IdleConns(),
+			MaxConns:        stat.MaxConns(),
+			AcquireCount:    stat.AcquireCount(),
+			AcquireDuration: stat.AcquireDuration().String(),
+			EmptyAcquires:   stat.EmptyAcquireCount(),
+			CanceledAcquire: stat.CanceledAcquireCount(),
+		}, nil)
+	}
+}
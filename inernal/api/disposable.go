@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// DomainBlocklist — множество доменов одноразовой почты в нижнем регистре, с которых нельзя регистрироваться
+type DomainBlocklist map[string]struct{}
+
+// LoadDomainBlocklist — читает домены из файла по одному на строку. Пустые строки и комментарии после #
+// пропускаются, регистр не важен
+func LoadDomainBlocklist(path string) (DomainBlocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть список доменов: %w", err)
+	}
+	defer file.Close()
+
+	blocklist := DomainBlocklist{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if domain := strings.ToLower(strings.TrimSpace(line)); domain != "" {
+			blocklist[domain] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать список доменов: %w", err)
+	}
+
+	return blocklist, nil
+}
+
+// blocked — сообщает, запрещён ли домен email. Запрещены и поддомены: если в списке mailinator.com,
+// то и eu.mailinator.com, иначе блокировку легко обойти. Email должен быть уже нормализован.
+// Домен — всё после последней @: локальная часть в кавычках может сама содержать @
+func (b DomainBlocklist) blocked(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 || len(b) == 0 {
+		return false
+	}
+
+	domain := strings.ToLower(email[at+1:])
+	for {
+		if _, ok := b[domain]; ok {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return false
+		}
+		domain = parent
+	}
+}
+
+// rejectDisposableEmail — отвечает 422, если email с домена одноразовой почты, и возвращает true.
+// Проверяется и при создании, и при смене email, иначе запрет обходится регистрацией с разрешённого адреса
+func (h *Handler) rejectDisposableEmail(w http.ResponseWriter, r *http.Request, email string) bool {
+	if !h.opts.DisposableDomains.blocked(email) {
+		return false
+	}
+
+	requestLogger(r).Warn("Email с домена одноразовой почты отклонён", "email", email)
+	response.ErrorWithDetails(w, http.StatusUnprocessableEntity, response.CodeDisposableEmail,
+		"Одноразовые почтовые адреса запрещены", []FieldError{{Field: "email", Message: "Домен одноразовой почты"}})
+	return true
+}
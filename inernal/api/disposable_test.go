@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+func TestDomainBlocklistBlocked(t *testing.T) {
+	blocklist := DomainBlocklist{"mailinator.com": {}, "tempmail.io": {}}
+
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{email: "alice@mailinator.com", want: true},
+		{email: "alice@MAILINATOR.COM", want: true},
+		{email: "alice@eu.mailinator.com", want: true},
+		{email: "alice@a.b.tempmail.io", want: true},
+		{email: "alice@example.com", want: false},
+		// Совпадение по суффиксу строки ещё не поддомен
+		{email: "alice@notmailinator.com", want: false},
+		{email: "alice@mailinator.com.example.org", want: false},
+		// Домен — после последней @, даже если @ есть в локальной части в кавычках
+		{email: `"bob@example.com"@mailinator.com`, want: true},
+		{email: `"bob@mailinator.com"@example.com`, want: false},
+		{email: "not-an-email", want: false},
+	}
+	for _, tt := range tests {
+		if got := blocklist.blocked(tt.email); got != tt.want {
+			t.Errorf("blocked(%q) = %v, ожидалось %v", tt.email, got, tt.want)
+		}
+	}
+
+	// Пустой список ничего не запрещает
+	if DomainBlocklist(nil).blocked("alice@mailinator.com") {
+		t.Error("пустой список запретил домен")
+	}
+}
+
+func TestLoadDomainBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	content := "# одноразовая почта\nMailinator.com\n\n  tempmail.io  # с комментарием\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	blocklist, err := LoadDomainBlocklist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocklist) != 2 || !blocklist.blocked("a@mailinator.com") || !blocklist.blocked("a@tempmail.io") {
+		t.Fatalf("прочитан список %v", blocklist)
+	}
+
+	if _, err := LoadDomainBlocklist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("отсутствующий файл должен быть ошибкой")
+	}
+}
+
+func TestCreateUserDisposableEmail(t *testing.T) {
+	opts := testOptions()
+	opts.DisposableDomains = DomainBlocklist{"mailinator.com": {}}
+	s := newTestServer(t, opts)
+
+	body := `{"username":"alice","email":"alice@eu.mailinator.com","password":"secret-password"}`
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users", body)
+	expectError(t, rec, http.StatusUnprocessableEntity, response.CodeDisposableEmail)
+
+	// Разрешённый домен проходит
+	s.createUser(t, "bob", "bob@example.com")
+
+	// Пакет не обходит запрет: в details указан индекс запрещённого элемента
+	batch := `[{"username":"carol","email":"carol@example.com","password":"secret-password"},` +
+		`{"username":"dave","email":"dave@mailinator.com","password":"secret-password"}]`
+	rec = s.do(t, http.MethodPost, APIPrefix+"/users/batch", batch)
+	apiErr := expectError(t, rec, http.StatusUnprocessableEntity, response.CodeDisposableEmail)
+	var details []batchItemError
+	if err := json.Unmarshal(apiErr.Details, &details); err != nil {
+		t.Fatal(err)
+	}
+	if len(details) != 1 || details[0].Index != 1 || details[0].Field != "email" {
+		t.Fatalf("details = %+v, ожидался элемент 1 с полем email", details)
+	}
+	if count := s.countUsers(t); count != 1 {
+		t.Fatalf("создано пользователей: %d, ожидался только bob", count)
+	}
+}
+
+func TestImportUsersDisposableEmail(t *testing.T) {
+	opts := testOptions()
+	opts.DisposableDomains = DomainBlocklist{"mailinator.com": {}}
+	s := newTestServer(t, opts)
+
+	// Строки с запрещённым доменом и его поддоменом попадают в failed, разрешённые импортируются
+	rec := s.importCSV(t, "username,email\nalice,alice@example.com\nbob,bob@mailinator.com\ncarol,carol@eu.mailinator.com\n")
+	var summary importSummary
+	decodeData(t, rec, &summary)
+	if rec.Code != http.StatusOK || summary.Imported != 1 || len(summary.Failed) != 2 {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	for i, wantRow := range []int{3, 4} {
+		if got := summary.Failed[i]; got.Row != wantRow || got.Field != "email" {
+			t.Fatalf("failed[%d] = %+v, ожидалась строка %d с полем email", i, got, wantRow)
+		}
+	}
+	if got := usernames(s.listUsers(t, "")); !slices.Equal(got, []string{"alice"}) {
+		t.Fatalf("импортированы %v, ожидалась только alice", got)
+	}
+}
+
+func TestChangeEmailToDisposable(t *testing.T) {
+	opts := testOptions()
+	opts.DisposableDomains = DomainBlocklist{"mailinator.com": {}}
+	s := newTestServer(t, opts)
+	user := s.createUser(t, "alice", "alice@example.com")
+	path := APIPrefix + "/users/" + user.ID.String()
+
+	// Зарегистрироваться с разрешённого адреса и затем сменить его на запрещённый нельзя
+	rec := s.do(t, http.MethodPut, path, `{"username":"alice","email":"alice@mailinator.com"}`)
+	expectError(t, rec, http.StatusUnprocessableEntity, response.CodeDisposableEmail)
+	rec = s.do(t, http.MethodPatch, path, `{"email":"alice@eu.mailinator.com"}`)
+	expectError(t, rec, http.StatusUnprocessableEntity, response.CodeDisposableEmail)
+
+	got, err := s.users.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != "alice@example.com" {
+		t.Fatalf("email сменился на %q", got.Email)
+	}
+
+	// Разрешённый домен и PATCH без email проходят
+	if rec := s.do(t, http.MethodPut, path, `{"username":"alice","email":"alice@example.org"}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT на разрешённый домен: статус %d, тело %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(t, http.MethodPatch, path, `{"username":"alice2"}`); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH имени: статус %d, тело %s", rec.Code, rec.Body)
+	}
+}
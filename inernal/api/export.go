@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// exportPageSize — сколько пользователей читается из базы за один запрос при выгрузке в CSV
+const exportPageSize = 500
+
+// csvHeader — заголовок CSV-выгрузки пользователей
+var csvHeader = []string{"id", "username", "email", "created_at"}
+
+// exportUsersCSVHandler — обработчик GET-запросов для выгрузки пользователей в CSV.
+// Принимает те же фильтры и сортировку, что и список. Пользователи читаются страницами по курсору
+// и сразу пишутся в ответ, поэтому вся таблица не держится в памяти
+func (h *Handler) exportUsersCSVHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный параметр фильтра", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery, err.Error())
+		return
+	}
+
+	sort, err := parseSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		requestLogger(r).Warn("Некорректный параметр сортировки", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery, err.Error())
+		return
+	}
+
+	params := repository.ListParams{
+		ListFilter: filter,
+		Limit:      exportPageSize,
+		Sort:       sort,
+	}
+
+	// Первую страницу читаем до отправки заголовков, чтобы на ошибку базы ещё можно было ответить 500
+	users, err := h.listPage(r.Context(), params)
+	if err != nil {
+		writeDBError(w, r, "Ошибка выборки из базы", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	// Браузер сохраняет ответ файлом, а не открывает его во вкладке
+	w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write(csvHeader)
+
+	exported := 0
+	for {
+		for _, user := range users {
+			_ = writer.Write([]string{user.ID.String(), user.Username, user.Email, user.CreatedAt.UTC().Format(time.RFC3339)})
+		}
+		exported += len(users)
+
+		// Отдаём клиенту каждую страницу сразу, не дожидаясь конца выгрузки
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			requestLogger(r).Warn("Клиент прервал выгрузку пользователей", "error", err, "exported", exported)
+			return
+		}
+
+		if len(users) < exportPageSize {
+			break
+		}
+
+		last := users[len(users)-1]
+		params.After = &repository.Cursor{CreatedAt: last.CreatedAt, Username: last.Username, ID: last.ID}
+		users, err = h.listPage(r.Context(), params)
+		if err != nil {
+			// Статус уже отправлен, поэтому выгрузка просто обрывается; клиент получит неполный файл
+			requestLogger(r).Error("Ошибка выборки из базы во время выгрузки", "error", err, "exported", exported)
+			return
+		}
+	}
+
+	requestLogger(r).Info("Пользователи выгружены в CSV", "count", exported)
+}
+
+// listPage — читает одну страницу пользователей с таймаутом на операцию с базой
+func (h *Handler) listPage(ctx context.Context, params repository.ListParams) ([]model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.opts.DBOpTimeout)
+	defer cancel()
+
+	return h.users.List(ctx, params)
+}
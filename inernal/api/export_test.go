@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// exportCSV — выгружает пользователей через GET /users.csv и разбирает ответ обратно в строки
+func (s *testServer) exportCSV(t *testing.T, query string) [][]string {
+	t.Helper()
+
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users.csv"+query, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("выгрузка %q: статус %d, тело %s", query, rec.Code, rec.Body)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+		t.Fatalf("Content-Type = %q", contentType)
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment") {
+		t.Fatalf("Content-Disposition = %q, браузер не сохранит файл", disposition)
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("ответ не CSV: %v", err)
+	}
+	if len(records) == 0 || !slices.Equal(records[0], csvHeader) {
+		t.Fatalf("заголовок %v, ожидался %v", records, csvHeader)
+	}
+
+	return records[1:]
+}
+
+// csvRow — строка выгрузки для пользователя
+func csvRow(user model.User) []string {
+	return []string{user.ID.String(), user.Username, user.Email, user.CreatedAt.UTC().Format(time.RFC3339)}
+}
+
+func TestExportUsersCSV(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	alice := s.createUser(t, "alice", "alice@example.com")
+	bob := s.createUser(t, "bob", "bob@example.com")
+	// Запятая и кавычки в значении экранируются средствами encoding/csv
+	quoted := model.User{Username: "carol", Email: `"carol,x"@example.com`}
+	if err := s.users.Create(context.Background(), &quoted); err != nil {
+		t.Fatal(err)
+	}
+
+	records := s.exportCSV(t, "?sort=username")
+	want := [][]string{csvRow(alice), csvRow(bob), csvRow(quoted)}
+	if !slices.EqualFunc(records, want, slices.Equal[[]string]) {
+		t.Fatalf("выгружено %v, ожидалось %v", records, want)
+	}
+
+	// Фильтры те же, что у JSON-списка
+	if records := s.exportCSV(t, "?q=ali"); !slices.EqualFunc(records, [][]string{csvRow(alice)}, slices.Equal[[]string]) {
+		t.Fatalf("выгрузка с q=ali: %v", records)
+	}
+	if rec := s.do(t, http.MethodDelete, APIPrefix+"/users/"+bob.ID.String(), ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("удаление: статус %d", rec.Code)
+	}
+	if records := s.exportCSV(t, "?sort=username"); len(records) != 2 {
+		t.Fatalf("удалённый пользователь попал в выгрузку: %v", records)
+	}
+
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users.csv?sort=password_hash", "")
+	expectError(t, rec, http.StatusBadRequest, response.CodeInvalidQuery)
+}
+
+func TestExportUsersCSVPages(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	// Больше одной страницы чтения: страницы стыкуются по курсору без повторов и пропусков
+	total := exportPageSize + 3
+	for i := range total {
+		user := model.User{Username: fmt.Sprintf("user_%04d", i), Email: fmt.Sprintf("user_%04d@example.com", i)}
+		if err := s.users.Create(context.Background(), &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records := s.exportCSV(t, "?sort=username")
+	if len(records) != total {
+		t.Fatalf("выгружено %d строк, ожидалось %d", len(records), total)
+	}
+	for i, record := range records {
+		if record[1] != fmt.Sprintf("user_%04d", i) {
+			t.Fatalf("строка %d: %v", i, record)
+		}
+	}
+}
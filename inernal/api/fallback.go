@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// WithJSONFallback оборачивает mux так, чтобы неизвестные маршруты и неподдерживаемые методы
+// получали ответ в том же JSON-формате ошибки, что и остальное API, а не текст по умолчанию
+func WithJSONFallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Маршрут не найден: выясняем у mux, отвечает он 404 или 405 и какие методы разрешены
+		probe := &fallbackProbe{header: make(http.Header)}
+		handler.ServeHTTP(probe, r)
+
+		switch probe.status {
+		case http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", probe.header.Get("Allow"))
+			response.Error(w, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, "Метод не поддерживается")
+		case http.StatusNotFound:
+			response.Error(w, http.StatusNotFound, response.CodeNotFound, "Маршрут не найден")
+		default:
+			// Например, перенаправление на путь со слешем в конце — оставляем поведение mux
+			mux.ServeHTTP(w, r)
+		}
+	})
+}
+
+// fallbackProbe — ResponseWriter, который только запоминает код и заголовки ответа mux, ничего не отправляя
+type fallbackProbe struct {
+	header http.Header
+	status int
+}
+
+func (p *fallbackProbe) Header() http.Header {
+	return p.header
+}
+
+func (p *fallbackProbe) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (p *fallbackProbe) WriteHeader(status int) {
+	if p.status == 0 {
+		p.status = status
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+func TestJSONFallbackNotFound(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	for _, path := range []string{"/no-such-route", APIPrefix + "/accounts", APIPrefix + "/users/1/friends"} {
+		rec := s.do(t, http.MethodGet, path, "")
+		expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+	}
+}
+
+func TestJSONFallbackMethodNotAllowed(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	rec := s.do(t, http.MethodPatch, APIPrefix+"/users", "")
+	expectError(t, rec, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed)
+
+	// Allow перечисляет методы, зарегистрированные для пути
+	allow := rec.Header().Get("Allow")
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+		if !strings.Contains(allow, method) {
+			t.Fatalf("Allow = %q, нет метода %s", allow, method)
+		}
+	}
+	if strings.Contains(allow, http.MethodPatch) {
+		t.Fatalf("Allow = %q содержит неподдерживаемый PATCH", allow)
+	}
+}
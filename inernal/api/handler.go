@@ -0,0 +1,347 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/middleware"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/tracing"
+)
+
+// APIPrefix — префикс текущей версии API, под которым регистрируются маршруты пользователей
+const APIPrefix = "/api/v1"
+
+// Pinger — всё, что нужно readiness-пробе от базы данных
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// MigrationChecker — источник списка неприменённых миграций для readiness-пробы
+type MigrationChecker interface {
+	PendingVersions(ctx context.Context) ([]int64, error)
+}
+
+// UserEvents — получатель событий о пользователях, например отправка вебхуков.
+// Вызовы не должны блокировать обработчик
+type UserEvents interface {
+	UserCreated(user model.User)
+}
+
+// Options — настройки обработчиков
+type Options struct {
+	// ReadinessTimeout — таймаут проверки базы в /readyz
+	ReadinessTimeout time.Duration
+	// DBOpTimeout — таймаут операций с базой в рамках одного запроса
+	DBOpTimeout time.Duration
+	// MaxBodyBytes — максимальный размер тела запроса в байтах
+	MaxBodyBytes int64
+	// BcryptCost — стоимость bcrypt-хеширования паролей
+	BcryptCost int
+	// LegacyRoutes — дополнительно обслуживать маршруты без префикса версии, например /users
+	LegacyRoutes bool
+	// IdempotencyTTL — сколько хранится ответ на запрос с заголовком Idempotency-Key
+	IdempotencyTTL time.Duration
+	// AdminToken — токен заголовка X-Admin-Token для административных маршрутов; пусто — они закрыты
+	AdminToken string
+	// DisposableDomains — домены одноразовой почты, на которые нельзя создать пользователя или сменить email; пусто — проверка выключена
+	DisposableDomains DomainBlocklist
+	// AdminMigrateEnabled — регистрировать POST /admin/migrate; иначе маршрута нет и он отвечает 404
+	AdminMigrateEnabled bool
+}
+
+// Handler — HTTP-обработчики сервиса. Зависимости передаются через конструктор,
+// поэтому в тестах хранилище можно заменить реализацией в памяти
+type Handler struct {
+	users      repository.UserRepository
+	keys       repository.IdempotencyStore
+	events     UserEvents
+	db         Pinger
+	migrations MigrationChecker
+	feed       *userFeed
+	saturation saturationTracker
+	opts       Options
+}
+
+// NewHandler создаёт обработчики. keys, events и migrations необязательны: без них не поддерживается
+// Idempotency-Key, не отправляются события и readiness-проба не проверяет миграции
+func NewHandler(users repository.UserRepository, keys repository.IdempotencyStore, events UserEvents, db Pinger, migrations MigrationChecker, opts Options) *Handler {
+	return &Handler{
+		users:      users,
+		keys:       keys,
+		events:     events,
+		db:         db,
+		migrations: migrations,
+		feed:       newUserFeed(),
+		opts:       opts,
+	}
+}
+
+// userCreated — публикует созданных пользователей в поток /users/stream и сообщает о них
+// получателю событий, если он задан
+func (h *Handler) userCreated(users ...model.User) {
+	for _, user := range users {
+		h.feed.publish(user)
+		if h.events != nil {
+			h.events.UserCreated(user)
+		}
+	}
+}
+
+// route — маршрут API: метод, путь без префикса версии и обработчик
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+}
+
+// Register регистрирует все маршруты сервиса в mux
+func (h *Handler) Register(mux *http.ServeMux) {
+	// Маршруты задаются вместе с методом: на остальные методы ServeMux сам отвечает 405 с заголовком Allow
+	routes := []route{
+		{http.MethodGet, "/users", h.listUsersHandler},
+		{http.MethodPost, "/users", h.idempotent(h.createUserHandler)},
+		{http.MethodDelete, "/users", h.admin(h.deleteUsersHandler)},
+		{http.MethodPost, "/users/batch", h.createUsersBatchHandler},
+		{http.MethodPost, "/users/import", h.importUsersHandler},
+		{http.MethodGet, "/users/count", h.countUsersHandler},
+		{http.MethodGet, "/users.csv", h.exportUsersCSVHandler},
+		{http.MethodGet, "/users/stream", h.streamUsersHandler},
+		{http.MethodGet, "/users/{id}", h.getUserHandler},
+		{http.MethodGet, "/users/by-email/{email}", h.getUserByEmailHandler},
+		{http.MethodPut, "/users/{id}", h.updateUserHandler},
+		{http.MethodPatch, "/users/{id}", h.patchUserHandler},
+		{http.MethodDelete, "/users/{id}", h.deleteUserHandler},
+		{http.MethodPost, "/users/{id}/restore", h.restoreUserHandler},
+	}
+
+	for _, rt := range routes {
+		mux.HandleFunc(rt.method+" "+APIPrefix+rt.path, rt.handler)
+		// Старые пути без версии оставлены на время перехода клиентов и помечены как устаревшие
+		if h.opts.LegacyRoutes {
+			mux.HandleFunc(rt.method+" "+rt.path, deprecated(rt.handler))
+		}
+	}
+
+	// Пробы оркестратора не относятся к версии API
+	// Liveness-проба для оркестратора контейнеров
+	mux.HandleFunc("GET /healthz", h.healthzHandler)
+	// Readiness-проба: сервис готов принимать трафик, только если доступна база
+	mux.HandleFunc("GET /readyz", h.readyzHandler)
+	// Сведения о сборке, как и пробы, нужны при разборе развёртываний и не зависят от версии API
+	mux.HandleFunc("GET /version", h.versionHandler)
+	// Запуск миграций меняет схему общей базы, поэтому маршрут появляется только по явной настройке
+	if h.opts.AdminMigrateEnabled {
+		mux.HandleFunc(http.MethodPost+" "+AdminMigratePath, h.admin(h.migrateHandler))
+	}
+}
+
+// deprecated — помечает ответы устаревшего маршрута заголовком Deprecation и ссылкой на замену
+func deprecated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+APIPrefix+r.URL.Path+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
+
+// unknownFieldPrefix — начало сообщения encoding/json о поле, которого нет в целевой структуре
+const unknownFieldPrefix = "json: unknown field "
+
+// decodeJSON — читает JSON-тело запроса в v, ограничивая его размер MaxBodyBytes.
+// Неизвестные поля отклоняются: опечатка вроде "emial" иначе превратилась бы в пустой email.
+// При ошибке сам отвечает клиенту (415 для тела не в JSON, 413 для слишком большого тела,
+// иначе 400) и возвращает false
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := requireJSONContentType(r); err != nil {
+		requestLogger(r).Warn("Неподдерживаемый тип тела запроса", "error", err)
+		response.Error(w, http.StatusUnsupportedMediaType, response.CodeUnsupportedMedia,
+			"Тело запроса должно быть в формате application/json")
+		return false
+	}
+
+	// MaxBytesReader прерывает чтение на лимите, не давая клиенту занять память огромным телом
+	r.Body = http.MaxBytesReader(w, r.Body, h.opts.MaxBodyBytes)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		return true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		requestLogger(r).Warn("Слишком большое тело запроса", "limit", maxBytesErr.Limit)
+		response.Error(w, http.StatusRequestEntityTooLarge, response.CodePayloadTooLarge,
+			fmt.Sprintf("Тело запроса не должно превышать %d байт", maxBytesErr.Limit))
+		return false
+	}
+
+	// encoding/json не экспортирует тип этой ошибки, поэтому имя поля достаём из сообщения
+	if field, ok := strings.CutPrefix(err.Error(), unknownFieldPrefix); ok {
+		field = strings.Trim(field, `"`)
+		requestLogger(r).Warn("Неизвестное поле в теле запроса", "field", field)
+		response.ErrorWithDetails(w, http.StatusBadRequest, response.CodeInvalidJSON,
+			fmt.Sprintf("Неизвестное поле %q", field), unknownFieldDetails{Field: field})
+		return false
+	}
+
+	requestLogger(r).Warn("Некорректный JSON в запросе", "error", err)
+	response.Error(w, http.StatusBadRequest, response.CodeInvalidJSON, "Некорректный JSON")
+	return false
+}
+
+// peekJSONArray — сообщает, начинается ли тело запроса с JSON-массива, по первому байту после пробелов.
+// Прочитанное возвращается в r.Body, так что тело затем целиком читает decodeJSON.
+// Лимит MaxBodyBytes действует уже здесь, чтобы тело из одних пробелов не читалось бесконечно
+func (h *Handler) peekJSONArray(w http.ResponseWriter, r *http.Request) bool {
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, h.opts.MaxBodyBytes))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, r.Body}
+
+	for {
+		b, err := body.ReadByte()
+		if err != nil {
+			// Ошибку чтения сообщит decodeJSON: повторное чтение вернёт её же
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		_ = body.UnreadByte()
+		return b == '['
+	}
+}
+
+// unknownFieldDetails — поле тела запроса, которое сервис не ожидает
+type unknownFieldDetails struct {
+	Field string `json:"field"`
+}
+
+// clientGone — сообщает, что клиент отключился и отвечать уже некому. Это не ошибка сервиса,
+// поэтому пишется в лог на уровне debug
+func clientGone(r *http.Request) bool {
+	if !errors.Is(r.Context().Err(), context.Canceled) {
+		return false
+	}
+
+	requestLogger(r).Debug("Клиент отключился, обработка запроса прервана")
+	return true
+}
+
+// writeDBError — логирует ошибку хранилища и отвечает клиенту: 503, пока база отключена
+// автоматическим выключателем или истёк общий дедлайн запроса, и 500 в остальных случаях.
+// Если клиент уже отключился, ответ не пишется
+func writeDBError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	if clientGone(r) {
+		return
+	}
+	if errors.Is(err, repository.ErrUnavailable) {
+		requestLogger(r).Warn(message, "error", err)
+		response.Error(w, http.StatusServiceUnavailable, response.CodeUnavailable, "База данных временно недоступна")
+		return
+	}
+	// Дедлайн самого запроса, а не таймаут операции с базой: отвечаем так же, как middleware.Timeout
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		requestLogger(r).Warn(message, "error", err)
+		response.Error(w, http.StatusServiceUnavailable, response.CodeTimeout, "Превышено время обработки запроса")
+		return
+	}
+
+	requestLogger(r).Error(message, "error", err)
+	response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Ошибка сервера")
+}
+
+// requireJSONContentType — проверяет, что клиент объявил тело как application/json;
+// параметры вроде charset=utf-8 допускаются
+func requireJSONContentType(r *http.Request) error {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return errors.New("не указан заголовок Content-Type")
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("некорректный заголовок Content-Type %q: %w", contentType, err)
+	}
+	if mediaType != "application/json" {
+		return fmt.Errorf("неподдерживаемый Content-Type %q", mediaType)
+	}
+
+	return nil
+}
+
+// parseUserID — извлекает UUID пользователя из пути запроса вида /users/{id}
+func parseUserID(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("некорректный идентификатор пользователя %q: %w", r.PathValue("id"), err)
+	}
+
+	return id, nil
+}
+
+// parseNonNegativeQueryInt — читает неотрицательное целое из query-параметра,
+// возвращая значение по умолчанию, если параметр не передан
+func parseNonNegativeQueryInt(r *http.Request, name string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("параметр %s должен быть неотрицательным целым числом", name)
+	}
+
+	return number, nil
+}
+
+// parseBoolQuery — читает логическое значение из query-параметра; отсутствующий параметр означает false
+func parseBoolQuery(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("параметр %s должен быть true или false", name)
+	}
+
+	return flag, nil
+}
+
+// requestLogger — возвращает логгер с атрибутами текущего HTTP-запроса, включая его идентификатор
+// и, если запрос аутентифицирован, идентификатор пользователя
+func requestLogger(r *http.Request) *slog.Logger {
+	logger := slog.With(
+		"method", r.Method,
+		"path", r.URL.Path,
+		"request_id", middleware.RequestIDFromContext(r.Context()),
+	).With(tracing.LogAttrs(r.Context())...)
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+		logger = logger.With("auth_user_id", userID)
+	}
+
+	return logger
+}
@@ -0,0 +1,527 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+func TestMain(m *testing.M) {
+	// Обработчики пишут в лог на каждый запрос; в выводе тестов он только мешает
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// testOptions — настройки обработчиков для тестов: минимальная стоимость bcrypt, чтобы тесты не тормозили
+func testOptions() Options {
+	return Options{
+		ReadinessTimeout: time.Second,
+		DBOpTimeout:      time.Second,
+		MaxBodyBytes:     1 << 20,
+		BcryptCost:       bcrypt.MinCost,
+		LegacyRoutes:     true,
+		IdempotencyTTL:   time.Hour,
+	}
+}
+
+// testServer — обработчики поверх хранилища в памяти вместе с самим хранилищем
+type testServer struct {
+	handler *Handler
+	users   *repository.MemoryUserRepository
+	mux     http.Handler
+}
+
+// newTestServer — собирает обработчики с хранилищем в памяти и единым JSON-ответом на неизвестные маршруты
+func newTestServer(t *testing.T, opts Options) *testServer {
+	t.Helper()
+
+	users := repository.NewMemoryUserRepository()
+	handler := NewHandler(users, repository.NewMemoryIdempotencyStore(), nil, nil, nil, opts)
+	mux := http.NewServeMux()
+	handler.Register(mux)
+
+	return &testServer{handler: handler, users: users, mux: WithJSONFallback(mux)}
+}
+
+// do — выполняет запрос и возвращает записанный ответ. headers — пары имя-значение
+func (s *testServer) do(t *testing.T, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	return rec
+}
+
+// createUser — создаёт пользователя через POST /api/v1/users и возвращает его из ответа
+func (s *testServer) createUser(t *testing.T, username, email string) model.User {
+	t.Helper()
+
+	body := `{"username":"` + username + `","email":"` + email + `","password":"secret-password"}`
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("создание пользователя %s: статус %d, тело %s", username, rec.Code, rec.Body)
+	}
+
+	var user model.User
+	decodeData(t, rec, &user)
+
+	return user
+}
+
+// decodeData — разбирает поле data успешного ответа в v
+func decodeData(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("ответ не JSON: %v, тело %s", err, rec.Body)
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		t.Fatalf("не удалось разобрать data: %v, тело %s", err, rec.Body)
+	}
+}
+
+// apiError — тело ответа с ошибкой
+type apiError struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Details json.RawMessage `json:"details"`
+}
+
+// decodeError — разбирает ошибку из ответа {"error":{...}}
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) apiError {
+	t.Helper()
+
+	var body struct {
+		Error apiError `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("ответ не JSON: %v, тело %s", err, rec.Body)
+	}
+
+	return body.Error
+}
+
+// expectError — проверяет статус и машиночитаемый код ошибки ответа
+func expectError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) apiError {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("статус %d, ожидался %d, тело %s", rec.Code, status, rec.Body)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("Content-Type ошибки %q, ожидался application/json", contentType)
+	}
+	apiErr := decodeError(t, rec)
+	if apiErr.Code != code {
+		t.Fatalf("код ошибки %q, ожидался %q", apiErr.Code, code)
+	}
+	if apiErr.Message == "" {
+		t.Fatalf("у ошибки %q нет message, тело %s", code, rec.Body)
+	}
+
+	return apiErr
+}
+
+func TestHandlerUserLifecycle(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	created := s.createUser(t, "alice", "alice@example.com")
+	if created.ID.String() == "" || created.CreatedAt.IsZero() {
+		t.Fatalf("у созданного пользователя не заполнены сгенерированные поля: %+v", created)
+	}
+
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users/"+created.ID.String(), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET: статус %d, тело %s", rec.Code, rec.Body)
+	}
+	var got model.User
+	decodeData(t, rec, &got)
+	if got.ID != created.ID || got.Email != "alice@example.com" {
+		t.Fatalf("GET вернул %+v, ожидался %+v", got, created)
+	}
+
+	rec = s.do(t, http.MethodGet, APIPrefix+"/users", "")
+	var list []model.User
+	decodeData(t, rec, &list)
+	if len(list) != 1 || list[0].ID != created.ID {
+		t.Fatalf("в списке %+v, ожидался один созданный пользователь", list)
+	}
+
+	rec = s.do(t, http.MethodDelete, APIPrefix+"/users/"+created.ID.String(), "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: статус %d, тело %s", rec.Code, rec.Body)
+	}
+
+	rec = s.do(t, http.MethodGet, APIPrefix+"/users/"+created.ID.String(), "")
+	expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+}
+
+func TestHandlerUsesInjectedRepository(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	// Пользователь, сохранённый напрямую в хранилище, виден через HTTP: обработчики не держат своего состояния
+	user := model.User{Username: "direct", Email: "direct@example.com"}
+	if err := s.users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users/"+user.ID.String(), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+}
+
+// recordedEvents — получатель событий, запоминающий имена созданных пользователей
+type recordedEvents struct {
+	usernames []string
+}
+
+func (e *recordedEvents) UserCreated(user model.User) {
+	e.usernames = append(e.usernames, user.Username)
+}
+
+func TestHandlerPublishesUserCreated(t *testing.T) {
+	events := &recordedEvents{}
+	handler := NewHandler(repository.NewMemoryUserRepository(), nil, events, nil, nil, testOptions())
+	mux := http.NewServeMux()
+	handler.Register(mux)
+	s := &testServer{handler: handler, mux: WithJSONFallback(mux)}
+
+	s.createUser(t, "alice", "alice@example.com")
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users/batch", batchBody("user", 2))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("пакет: статус %d, тело %s", rec.Code, rec.Body)
+	}
+
+	// Неудачное создание события не порождает
+	rec = s.do(t, http.MethodPost, APIPrefix+"/users",
+		`{"username":"alice2","email":"alice@example.com","password":"secret-password"}`)
+	expectError(t, rec, http.StatusConflict, response.CodeEmailTaken)
+
+	if want := []string{"alice", "user_0", "user_1"}; !slices.Equal(events.usernames, want) {
+		t.Fatalf("события о %v, ожидались о %v", events.usernames, want)
+	}
+}
+
+func TestHandlerErrorShape(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{name: "битый JSON", method: http.MethodPost, path: APIPrefix + "/users", body: `{"username":`,
+			status: http.StatusBadRequest, code: response.CodeInvalidJSON},
+		{name: "пустые поля", method: http.MethodPost, path: APIPrefix + "/users", body: `{}`,
+			status: http.StatusBadRequest, code: response.CodeValidationFailed},
+		{name: "некорректный id", method: http.MethodGet, path: APIPrefix + "/users/42",
+			status: http.StatusBadRequest, code: response.CodeInvalidID},
+		{name: "неизвестный маршрут", method: http.MethodGet, path: "/no-such-route",
+			status: http.StatusNotFound, code: response.CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(t, tt.method, tt.path, tt.body)
+			expectError(t, rec, tt.status, tt.code)
+
+			// Кроме error в теле ничего нет: клиенты различают успех и ошибку по верхнему ключу
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := body["error"]; !ok || len(body) != 1 {
+				t.Fatalf("тело ошибки %s, ожидался только объект error", rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandlerResponseEnvelope(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+	s.createUser(t, "bob", "bob@example.com")
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		dataKind byte
+		wantMeta bool
+	}{
+		{name: "создание", method: http.MethodPost, path: APIPrefix + "/users",
+			body: `{"username":"carol","email":"carol@example.com","password":"secret-password"}`, dataKind: '{'},
+		{name: "один пользователь", method: http.MethodGet, path: APIPrefix + "/users/" + user.ID.String(), dataKind: '{'},
+		{name: "список", method: http.MethodGet, path: APIPrefix + "/users?limit=1", dataKind: '[', wantMeta: true},
+		{name: "подсчёт", method: http.MethodGet, path: APIPrefix + "/users/count", dataKind: '{'},
+		{name: "healthz", method: http.MethodGet, path: "/healthz", dataKind: '{'},
+		{name: "version", method: http.MethodGet, path: "/version", dataKind: '{'},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(t, tt.method, tt.path, tt.body)
+			if rec.Code >= http.StatusBadRequest {
+				t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("Content-Type = %q", ct)
+			}
+
+			// Верхний уровень — только data и, для списков, meta
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			data, ok := body["data"]
+			if !ok || len(data) == 0 || data[0] != tt.dataKind {
+				t.Fatalf("data = %s, ожидалось значение, начинающееся с %q", data, tt.dataKind)
+			}
+			wantKeys := 1
+			if tt.wantMeta {
+				wantKeys = 2
+			}
+			if _, hasMeta := body["meta"]; hasMeta != tt.wantMeta || len(body) != wantKeys {
+				t.Fatalf("тело %s: ожидались только data и meta=%v", rec.Body, tt.wantMeta)
+			}
+		})
+	}
+
+	// В meta списка — сведения о пагинации
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users?limit=1&offset=1", "")
+	var body struct {
+		Meta listMeta `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Meta.Total != 3 || body.Meta.Limit != 1 || body.Meta.Offset != 1 {
+		t.Fatalf("meta = %+v, ожидалось total=3, limit=1, offset=1", body.Meta)
+	}
+}
+
+func TestHandlerRejectsUnknownFields(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+	userPath := APIPrefix + "/users/" + user.ID.String()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		field  string
+	}{
+		{name: "лишнее поле", method: http.MethodPost, path: APIPrefix + "/users",
+			body: `{"username":"bob","email":"bob@example.com","password":"secret-password","role":"admin"}`, field: "role"},
+		{name: "опечатка в имени поля", method: http.MethodPost, path: APIPrefix + "/users",
+			body: `{"username":"bob","emial":"bob@example.com","password":"secret-password"}`, field: "emial"},
+		{name: "PUT", method: http.MethodPut, path: userPath,
+			body: `{"username":"alice","email":"alice@example.com","admin":true}`, field: "admin"},
+		{name: "PATCH", method: http.MethodPatch, path: userPath, body: `{"usrname":"alice2"}`, field: "usrname"},
+		{name: "элемент пакета", method: http.MethodPost, path: APIPrefix + "/users/batch",
+			body: `[{"username":"bob","email":"bob@example.com","password":"secret-password","emial":"x"}]`, field: "emial"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(t, tt.method, tt.path, tt.body)
+			apiErr := expectError(t, rec, http.StatusBadRequest, response.CodeInvalidJSON)
+			// И сообщение, и details называют поле, на котором споткнулся разбор
+			if !strings.Contains(apiErr.Message, tt.field) {
+				t.Fatalf("сообщение %q не называет поле %q", apiErr.Message, tt.field)
+			}
+			var details unknownFieldDetails
+			if err := json.Unmarshal(apiErr.Details, &details); err != nil || details.Field != tt.field {
+				t.Fatalf("details = %s, ожидалось поле %q", apiErr.Details, tt.field)
+			}
+		})
+	}
+
+	// Ни один запрос не изменил хранилище
+	if count := s.countUsers(t); count != 1 {
+		t.Fatalf("в хранилище %d пользователей, ожидался один", count)
+	}
+	rec := s.do(t, http.MethodGet, userPath, "")
+	var got model.User
+	decodeData(t, rec, &got)
+	if got.Username != "alice" {
+		t.Fatalf("пользователь изменён: %+v", got)
+	}
+}
+
+func TestHandlerMaxBodySize(t *testing.T) {
+	opts := testOptions()
+	opts.MaxBodyBytes = 256
+	s := newTestServer(t, opts)
+
+	// Тело ровно на лимите принимается: пробелы перед JSON допустимы
+	valid := `{"username":"alice","email":"alice@example.com","password":"secret-password"}`
+	atLimit := strings.Repeat(" ", int(opts.MaxBodyBytes)-len(valid)) + valid
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users", atLimit)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("тело на лимите: статус %d, тело %s", rec.Code, rec.Body)
+	}
+
+	// На байт больше — 413, в том числе в обработчиках с предварительным чтением тела
+	overLimit := " " + strings.Replace(atLimit, "alice", "bobby", 2)
+	for _, headers := range [][]string{nil, {IdempotencyKeyHeader, "key-1"}} {
+		rec = s.do(t, http.MethodPost, APIPrefix+"/users", overLimit, headers...)
+		expectError(t, rec, http.StatusRequestEntityTooLarge, response.CodePayloadTooLarge)
+	}
+	rec = s.do(t, http.MethodPut, APIPrefix+"/users/"+uuid.NewString(), overLimit)
+	expectError(t, rec, http.StatusRequestEntityTooLarge, response.CodePayloadTooLarge)
+
+	if count := s.countUsers(t); count != 1 {
+		t.Fatalf("пользователей %d, ожидался только созданный телом на лимите", count)
+	}
+}
+
+func TestHandlerRequiresJSONContentType(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+
+	requests := []struct{ method, path, body string }{
+		{method: http.MethodPost, path: "/users", body: `{"username":"bob","email":"bob@example.com","password":"secret-password"}`},
+		{method: http.MethodPut, path: "/users/" + user.ID.String(), body: `{"username":"alice","email":"alice2@example.com"}`},
+		{method: http.MethodPost, path: "/users/batch", body: `[{"username":"carol","email":"carol@example.com","password":"secret-password"}]`},
+	}
+	for _, req := range requests {
+		// Неверный и отсутствующий заголовок: обработчик не пытается разобрать тело
+		for _, contentType := range []string{"application/x-www-form-urlencoded", "text/plain", "application/json-seq", ""} {
+			rec := s.do(t, req.method, APIPrefix+req.path, req.body, "Content-Type", contentType)
+			expectError(t, rec, http.StatusUnsupportedMediaType, response.CodeUnsupportedMedia)
+		}
+
+		// Параметр charset допускается
+		rec := s.do(t, req.method, APIPrefix+req.path, req.body, "Content-Type", "application/json; charset=utf-8")
+		if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+			t.Fatalf("%s %s с charset: статус %d, тело %s", req.method, req.path, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestRequireJSONContentType(t *testing.T) {
+	for contentType, wantErr := range map[string]bool{
+		"application/json":                false,
+		"Application/JSON":                false,
+		"application/json; charset=utf-8": false,
+		"application/xml":                 true,
+		"application/json; =":             true,
+		"":                                true,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		req.Header.Set("Content-Type", contentType)
+		if err := requireJSONContentType(req); (err != nil) != wantErr {
+			t.Errorf("Content-Type %q: ошибка %v, ожидалась ошибка: %v", contentType, err, wantErr)
+		}
+	}
+}
+
+func TestHandlerMethodRouting(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+	byID := APIPrefix + "/users/" + user.ID.String()
+
+	// У каждого метода свой обработчик: его видно по коду ответа и по результату
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{method: http.MethodGet, path: APIPrefix + "/users", status: http.StatusOK},
+		{method: http.MethodPost, path: APIPrefix + "/users", body: `{"username":"bob","email":"bob@example.com","password":"secret-password"}`, status: http.StatusCreated},
+		{method: http.MethodGet, path: byID, status: http.StatusOK},
+		{method: http.MethodPut, path: byID, body: `{"username":"alice","email":"alice2@example.com"}`, status: http.StatusOK},
+		{method: http.MethodPatch, path: byID, body: `{"username":"alice3"}`, status: http.StatusOK},
+		{method: http.MethodDelete, path: byID, status: http.StatusNoContent},
+		{method: http.MethodPost, path: byID + "/restore", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := s.do(t, tt.method, tt.path, tt.body)
+		if rec.Code != tt.status {
+			t.Fatalf("%s %s: статус %d, ожидался %d, тело %s", tt.method, tt.path, rec.Code, tt.status, rec.Body)
+		}
+	}
+
+	// PUT и PATCH дошли до своих обработчиков: применились оба изменения
+	var got model.User
+	decodeData(t, s.do(t, http.MethodGet, byID, ""), &got)
+	if got.Username != "alice3" || got.Email != "alice2@example.com" {
+		t.Fatalf("после PUT и PATCH получен %+v", got)
+	}
+
+	for _, tt := range []struct{ method, path string }{
+		{method: http.MethodPut, path: APIPrefix + "/users"},
+		{method: http.MethodPost, path: byID},
+		{method: http.MethodGet, path: byID + "/restore"},
+	} {
+		rec := s.do(t, tt.method, tt.path, "")
+		expectError(t, rec, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed)
+		if rec.Header().Get("Allow") == "" {
+			t.Fatalf("%s %s: нет заголовка Allow", tt.method, tt.path)
+		}
+	}
+}
+
+func TestHandlerVersionedAndLegacyRoutes(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+
+	// На время перехода оба пути ведут к одному обработчику; старый помечен как устаревший
+	for _, path := range []string{APIPrefix + "/users/" + user.ID.String(), "/users/" + user.ID.String()} {
+		rec := s.do(t, http.MethodGet, path, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: статус %d, тело %s", path, rec.Code, rec.Body)
+		}
+	}
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users", "")
+	if rec.Header().Get("Deprecation") != "" {
+		t.Fatal("версионированный путь помечен как устаревший")
+	}
+	rec = s.do(t, http.MethodGet, "/users", "")
+	if rec.Header().Get("Deprecation") != "true" || !strings.Contains(rec.Header().Get("Link"), APIPrefix+"/users") {
+		t.Fatalf("устаревший путь: Deprecation %q, Link %q", rec.Header().Get("Deprecation"), rec.Header().Get("Link"))
+	}
+
+	// Без LegacyRoutes остаются только пути с префиксом версии
+	opts := testOptions()
+	opts.LegacyRoutes = false
+	s = newTestServer(t, opts)
+	if rec := s.do(t, http.MethodGet, APIPrefix+"/users", ""); rec.Code != http.StatusOK {
+		t.Fatalf("версионированный путь: статус %d", rec.Code)
+	}
+	expectError(t, s.do(t, http.MethodGet, "/users", ""), http.StatusNotFound, response.CodeNotFound)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// healthzHandler — liveness-проба: сообщает только о том, что процесс жив.
+// Намеренно не обращается к базе, чтобы отвечать даже при недоступном Postgres
+func (h *Handler) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	response.Data(w, http.StatusOK, map[string]string{"status": statusOK}, nil)
+}
+
+// CircuitStater — хранилище с автоматическим выключателем, сообщающее его состояние
+type CircuitStater interface {
+	CircuitState() string
+}
+
+// findCircuitStater — ищет выключатель в цепочке обёрток хранилища, например под кэшем
+func findCircuitStater(users repository.UserRepository) (CircuitStater, bool) {
+	for users != nil {
+		if stater, ok := users.(CircuitStater); ok {
+			return stater, true
+		}
+		unwrapper, ok := users.(interface {
+			Unwrap() repository.UserRepository
+		})
+		if !ok {
+			break
+		}
+		users = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// circuitOpen — состояние разомкнутого выключателя в CircuitStater
+const circuitOpen = "open"
+
+// Значения поля status в ответе readiness-пробы
+const (
+	statusOK       = "ok"
+	statusDegraded = "degraded"
+)
+
+// saturationTracker — определяет насыщение пула между пробами: все соединения заняты
+// и с прошлой пробы появились запросы, которым пришлось ждать свободного соединения
+type saturationTracker struct {
+	lastEmptyAcquires atomic.Int64
+}
+
+// poolUsage — часть статистики пула, по которой определяется насыщение; ей удовлетворяет *pgxpool.Stat
+type poolUsage interface {
+	AcquiredConns() int32
+	MaxConns() int32
+	EmptyAcquireCount() int64
+}
+
+// saturated — сравнивает статистику пула с прошлой пробой и запоминает текущее число ожиданий
+func (t *saturationTracker) saturated(stat poolUsage) bool {
+	emptyAcquires := stat.EmptyAcquireCount()
+	previous := t.lastEmptyAcquires.Swap(emptyAcquires)
+
+	return stat.AcquiredConns() >= stat.MaxConns() && emptyAcquires > previous
+}
+
+// readyzHandler — readiness-проба: проверяет доступность базы данных, состояние выключателя и применённость миграций.
+// Пока Postgres недоступен, выключатель разомкнут или схема отстаёт от миграций, отвечает 503, чтобы трафик не направлялся на этот экземпляр.
+// Насыщенный пул не повод снимать трафик: проба отвечает 200 со status=degraded, чтобы на это срабатывали алерты
+func (h *Handler) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	status := map[string]any{"status": statusOK, "degraded": false}
+	if stater, ok := findCircuitStater(h.users); ok {
+		state := stater.CircuitState()
+		status["circuit_breaker"] = state
+		if state == circuitOpen {
+			requestLogger(r).Warn("Выключатель базы данных разомкнут")
+			response.Error(w, http.StatusServiceUnavailable, response.CodeUnavailable, "Выключатель базы данных разомкнут")
+			return
+		}
+	}
+
+	// Насыщение проверяем до Ping: проба сама занимает соединение из пула
+	if pool, ok := h.db.(PoolStatter); ok {
+		stat := pool.Stat()
+		status["pool"] = map[string]int32{"acquired_conns": stat.AcquiredConns(), "max_conns": stat.MaxConns()}
+		if h.saturation.saturated(stat) {
+			requestLogger(r).Warn("Пул соединений с базой исчерпан, запросы ждут соединения",
+				"acquired_conns", stat.AcquiredConns(), "max_conns", stat.MaxConns())
+			status["status"] = statusDegraded
+			status["degraded"] = true
+		}
+	}
+
+	// Короткий таймаут, чтобы проба не зависала при проблемах с сетью
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.ReadinessTimeout)
+	defer cancel()
+
+	if err := h.db.Ping(ctx); err != nil {
+		requestLogger(r).Warn("База данных недоступна", "error", err)
+		response.Error(w, http.StatusServiceUnavailable, response.CodeUnavailable, "База данных недоступна: "+err.Error())
+		return
+	}
+
+	// Доступная база со старой схемой — тоже не готовность: такой экземпляр ещё не догнал миграции
+	if h.migrations != nil {
+		pending, err := h.migrations.PendingVersions(ctx)
+		if err != nil {
+			requestLogger(r).Warn("Не удалось проверить миграции", "error", err)
+			response.Error(w, http.StatusServiceUnavailable, response.CodeUnavailable, "Не удалось проверить миграции")
+			return
+		}
+		if len(pending) > 0 {
+			requestLogger(r).Warn("Есть неприменённые миграции", "pending", pending)
+			response.Error(w, http.StatusServiceUnavailable, response.CodeUnavailable,
+				fmt.Sprintf("Не применено миграций: %d", len(pending)))
+			return
+		}
+	}
+
+	response.Data(w, http.StatusOK, status, nil)
+}
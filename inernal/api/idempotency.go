@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+const (
+	// IdempotencyKeyHeader — заголовок, которым клиент помечает повторяемый запрос
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// maxIdempotencyKeyLength — ограничение длины ключа, чтобы не хранить произвольно большие строки
+	maxIdempotencyKeyLength = 255
+	// idempotencyPendingTTL — срок брони ключа на время выполнения запроса; если процесс упадёт,
+	// не сохранив ответ, ключ освободится через это время, а не через IdempotencyTTL
+	idempotencyPendingTTL = time.Minute
+)
+
+// replayedHeaders — заголовки исходного ответа, которые сохраняются и возвращаются при повторе
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// idempotent — оборачивает обработчик поддержкой заголовка Idempotency-Key: ответ на первый запрос
+// сохраняется, а повтор с тем же ключом и телом получает его без повторного выполнения.
+// Тот же ключ с другим телом отклоняется с 409, как и повтор, пока исходный запрос ещё выполняется.
+// Без заголовка запрос обрабатывается как обычно
+func (h *Handler) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || h.keys == nil {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			requestLogger(r).Warn("Слишком длинный ключ идемпотентности", "length", len(key))
+			response.Error(w, http.StatusBadRequest, response.CodeValidationFailed,
+				fmt.Sprintf("Заголовок %s не должен быть длиннее %d символов", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		// Тело читаем заранее: нужен его хеш, а обработчику оно передаётся заново
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.opts.MaxBodyBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				requestLogger(r).Warn("Слишком большое тело запроса", "limit", maxBytesErr.Limit)
+				response.Error(w, http.StatusRequestEntityTooLarge, response.CodePayloadTooLarge,
+					fmt.Sprintf("Тело запроса не должно превышать %d байт", maxBytesErr.Limit))
+				return
+			}
+
+			requestLogger(r).Warn("Ошибка чтения тела запроса", "error", err)
+			response.Error(w, http.StatusBadRequest, response.CodeInvalidJSON, "Не удалось прочитать тело запроса")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(hash[:])
+
+		ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+		defer cancel()
+
+		// Бронируем ключ до выполнения запроса: параллельный запрос с тем же ключом увидит бронь и не выполнится
+		record, reserved, err := h.keys.Reserve(ctx, repository.IdempotencyRecord{
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(idempotencyPendingTTL),
+		})
+		if err != nil {
+			requestLogger(r).Error("Ошибка бронирования ключа идемпотентности", "error", err)
+			response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Ошибка сервера")
+			return
+		}
+		if !reserved {
+			switch {
+			case record.RequestHash != requestHash:
+				requestLogger(r).Warn("Ключ идемпотентности использован с другим телом запроса")
+				response.Error(w, http.StatusConflict, response.CodeIdempotencyReuse,
+					"Ключ идемпотентности уже использован с другим телом запроса")
+			case record.Pending():
+				requestLogger(r).Warn("Запрос с этим ключом идемпотентности ещё выполняется")
+				w.Header().Set("Retry-After", "1")
+				response.Error(w, http.StatusConflict, response.CodeIdempotencyInProgress,
+					"Запрос с этим ключом идемпотентности ещё выполняется, повторите позже")
+			default:
+				requestLogger(r).Info("Повтор запроса по ключу идемпотентности", "status", record.StatusCode)
+				replay(w, record)
+			}
+			return
+		}
+
+		rec := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			// Бронь без ответа снимаем и при панике обработчика, иначе ключ будет занят до истечения брони
+			if completed {
+				return
+			}
+			storeCtx, storeCancel := h.storeContext(r)
+			defer storeCancel()
+			if err := h.keys.Release(storeCtx, key); err != nil {
+				requestLogger(r).Error("Ошибка снятия брони ключа идемпотентности", "error", err)
+			}
+		}()
+
+		next(rec, r)
+
+		// Ошибки сервера не сохраняем: клиент должен иметь возможность повторить такой запрос
+		if rec.status >= http.StatusInternalServerError {
+			return
+		}
+
+		headers := make(map[string]string, len(replayedHeaders))
+		for _, name := range replayedHeaders {
+			if value := rec.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+
+		storeCtx, storeCancel := h.storeContext(r)
+		defer storeCancel()
+
+		// Ответ уже отправлен клиенту, поэтому ошибку сохранения только логируем
+		err = h.keys.Complete(storeCtx, repository.IdempotencyRecord{
+			Key:         key,
+			RequestHash: requestHash,
+			StatusCode:  rec.status,
+			Headers:     headers,
+			Body:        rec.body.Bytes(),
+			ExpiresAt:   time.Now().Add(h.opts.IdempotencyTTL),
+		})
+		if err != nil {
+			requestLogger(r).Error("Ошибка сохранения ключа идемпотентности", "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// storeContext — контекст для записи ключа после выполнения запроса. Срок отсчитывается заново, а отмена
+// запроса не учитывается: ответ уже готов и его нужно сохранить, даже если клиент отключился
+func (h *Handler) storeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(r.Context()), h.opts.DBOpTimeout)
+}
+
+// replay — отправляет клиенту сохранённый ответ, помечая его заголовком Idempotent-Replayed
+func replay(w http.ResponseWriter, record repository.IdempotencyRecord) {
+	for name, value := range record.Headers {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.Header().Set("Content-Length", strconv.Itoa(len(record.Body)))
+	w.WriteHeader(record.StatusCode)
+	_, _ = w.Write(record.Body)
+}
+
+// captureWriter — пропускает ответ к клиенту и одновременно запоминает его статус и тело
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap даёт http.ResponseController доступ к исходному ResponseWriter
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+const idempotentBody = `{"username":"alice","email":"alice@example.com","password":"secret-password"}`
+
+// countUsers — число активных пользователей в хранилище теста
+func (s *testServer) countUsers(t *testing.T) int64 {
+	t.Helper()
+
+	count, err := s.users.Count(context.Background(), repository.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	first := s.do(t, http.MethodPost, APIPrefix+"/users", idempotentBody, IdempotencyKeyHeader, "key-1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("первый запрос: статус %d, тело %s", first.Code, first.Body)
+	}
+
+	second := s.do(t, http.MethodPost, APIPrefix+"/users", idempotentBody, IdempotencyKeyHeader, "key-1")
+	if second.Code != http.StatusCreated {
+		t.Fatalf("повтор: статус %d, тело %s", second.Code, second.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("повтор не помечен заголовком Idempotent-Replayed")
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Fatalf("повтор вернул другое тело:\n%s\nожидалось:\n%s", second.Body, first.Body)
+	}
+	if second.Header().Get("Location") != first.Header().Get("Location") {
+		t.Fatalf("Location повтора %q, у исходного ответа %q", second.Header().Get("Location"), first.Header().Get("Location"))
+	}
+	if count := s.countUsers(t); count != 1 {
+		t.Fatalf("создано пользователей: %d, ожидался один", count)
+	}
+}
+
+func TestIdempotencyBodyMismatch(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	first := s.do(t, http.MethodPost, APIPrefix+"/users", idempotentBody, IdempotencyKeyHeader, "key-1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("первый запрос: статус %d, тело %s", first.Code, first.Body)
+	}
+
+	other := `{"username":"bob","email":"bob@example.com","password":"secret-password"}`
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users", other, IdempotencyKeyHeader, "key-1")
+	expectError(t, rec, http.StatusConflict, response.CodeIdempotencyReuse)
+	if count := s.countUsers(t); count != 1 {
+		t.Fatalf("создано пользователей: %d, ожидался один", count)
+	}
+}
+
+func TestIdempotencyRequestInProgress(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	// Имитируем запрос с тем же ключом и телом, который ещё выполняется: бронь без ответа
+	hash := sha256.Sum256([]byte(idempotentBody))
+	_, reserved, err := s.handler.keys.Reserve(context.Background(), repository.IdempotencyRecord{
+		Key:         "busy",
+		RequestHash: hex.EncodeToString(hash[:]),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil || !reserved {
+		t.Fatalf("бронь: reserved=%v, err=%v", reserved, err)
+	}
+
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users", idempotentBody, IdempotencyKeyHeader, "busy")
+	expectError(t, rec, http.StatusConflict, response.CodeIdempotencyInProgress)
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("нет заголовка Retry-After")
+	}
+	if count := s.countUsers(t); count != 0 {
+		t.Fatalf("создано пользователей: %d, пока исходный запрос выполняется", count)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnFailure(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	// Ответ 5xx не сохраняется: после него ключ снова свободен и запрос можно повторить
+	failing := s.handler.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Ошибка сервера")
+	})
+	req := httptest.NewRequest(http.MethodPost, APIPrefix+"/users", strings.NewReader(idempotentBody))
+	req.Header.Set(IdempotencyKeyHeader, "retry")
+	failing(httptest.NewRecorder(), req)
+
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users", idempotentBody, IdempotencyKeyHeader, "retry")
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("повтор после 5xx: статус %d, тело %s", rec.Code, rec.Body)
+	}
+}
+
+func TestIdempotencyConcurrentRequests(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	const clients = 10
+
+	var wg sync.WaitGroup
+	statuses := make([]int, clients)
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := s.do(t, http.MethodPost, APIPrefix+"/users", idempotentBody, IdempotencyKeyHeader, "race")
+			statuses[i] = rec.Code
+		}()
+	}
+	wg.Wait()
+
+	// Один запрос создаёт пользователя, остальные получают его ответ или 409, пока он выполняется
+	for _, status := range statuses {
+		if status != http.StatusCreated && status != http.StatusConflict {
+			t.Fatalf("неожиданный статус %d среди %v", status, statuses)
+		}
+	}
+	if count := s.countUsers(t); count != 1 {
+		t.Fatalf("создано пользователей: %d, ожидался один", count)
+	}
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// importFileField — имя поля multipart-формы с CSV-файлом
+const importFileField = "file"
+
+// importHeader — ожидаемые столбцы CSV-файла импорта в этом порядке
+var importHeader = []string{"username", "email"}
+
+// importRowError — ошибка проверки строки CSV; Row — номер строки в файле, заголовок считается первой строкой
+type importRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// importSummary — итог импорта: сколько пользователей создано и какие строки отклонены
+type importSummary struct {
+	Imported int              `json:"imported"`
+	Failed   []importRowError `json:"failed"`
+}
+
+// importUsersHandler — обработчик POST-запросов для импорта пользователей из CSV-файла
+// в multipart-поле file со столбцами username,email. Корректные строки сохраняются в одной транзакции,
+// некорректные перечисляются в ответе с номерами строк и причинами
+func (h *Handler) importUsersHandler(w http.ResponseWriter, r *http.Request) {
+	// Ограничиваем размер загрузки так же, как для JSON-тел
+	r.Body = http.MaxBytesReader(w, r.Body, h.opts.MaxBodyBytes)
+
+	file, err := importFile(r)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный запрос импорта", "error", err)
+		if !writeBodyTooLarge(w, err) {
+			response.Error(w, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		}
+		return
+	}
+
+	users, failed, err := readImportCSV(file, h.opts.DisposableDomains)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный CSV-файл импорта", "error", err)
+		if !writeBodyTooLarge(w, err) {
+			response.Error(w, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		}
+		return
+	}
+	if len(users) > maxBatchSize {
+		requestLogger(r).Warn("Слишком много пользователей в файле импорта", "size", len(users))
+		response.Error(w, http.StatusRequestEntityTooLarge, response.CodePayloadTooLarge,
+			fmt.Sprintf("В файле не может быть больше %d пользователей", maxBatchSize))
+		return
+	}
+
+	// Разбор большого файла занимает время: если клиент не дождался, в базу ничего не пишем
+	if clientGone(r) {
+		return
+	}
+
+	if len(users) > 0 {
+		// Контекст с таймаутом для выполнения запроса к базе
+		ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+		defer cancel()
+
+		if err := h.users.CreateBatch(ctx, users); err != nil {
+			// Конфликт уникальности отменяет весь импорт: транзакция откатывается целиком
+			if writeConflict(w, r, err) {
+				return
+			}
+
+			writeDBError(w, r, "Ошибка пакетной вставки в базу", err)
+			return
+		}
+		h.userCreated(users...)
+	}
+
+	requestLogger(r).Info("Пользователи импортированы из CSV", "imported", len(users), "failed", len(failed))
+
+	response.Data(w, http.StatusOK, importSummary{Imported: len(users), Failed: failed}, nil)
+}
+
+// importFile — находит в multipart-теле запроса часть с CSV-файлом, не загружая форму целиком в память
+func importFile(r *http.Request) (io.Reader, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("ожидается тело multipart/form-data с полем %s: %w", importFileField, err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("в форме нет поля %s", importFileField)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать форму: %w", err)
+		}
+		if part.FormName() == importFileField {
+			return part, nil
+		}
+	}
+}
+
+// readImportCSV — разбирает CSV-файл импорта: проверяет заголовок и каждую строку по правилам создания
+// пользователя, включая запрет одноразовой почты из blocklist. Возвращает корректных пользователей и ошибки остальных строк
+func readImportCSV(file io.Reader, blocklist DomainBlocklist) ([]model.User, []importRowError, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = len(importHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("файл пуст")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось прочитать заголовок: %w", err)
+	}
+	for i, column := range header {
+		if !strings.EqualFold(strings.TrimSpace(column), importHeader[i]) {
+			return nil, nil, fmt.Errorf("заголовок файла должен быть %s", strings.Join(importHeader, ","))
+		}
+	}
+
+	var users []model.User
+	failed := []importRowError{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// Строка с другим числом столбцов отклоняется, остальные продолжают разбираться
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				failed = append(failed, importRowError{Row: parseErr.StartLine, Message: "Неверное число столбцов"})
+				continue
+			}
+			return nil, nil, fmt.Errorf("не удалось прочитать файл: %w", err)
+		}
+		// Номер строки берём у самого CSV-читателя: значение в кавычках может занимать несколько строк
+		row, _ := reader.FieldPos(0)
+
+		user := model.User{Username: strings.TrimSpace(record[0]), Email: record[1]}
+		if validationErr := validateUser(&user); validationErr != nil {
+			for _, fieldErr := range validationErr.Fields {
+				failed = append(failed, importRowError{Row: row, Field: fieldErr.Field, Message: fieldErr.Message})
+			}
+			continue
+		}
+		// Импорт, как и пакет, не должен становиться обходом запрета одноразовой почты
+		if blocklist.blocked(user.Email) {
+			failed = append(failed, importRowError{Row: row, Field: "email", Message: "Домен одноразовой почты"})
+			continue
+		}
+		users = append(users, user)
+	}
+
+	return users, failed, nil
+}
+
+// writeBodyTooLarge — отвечает 413, если err вызвана превышением лимита MaxBytesReader
+func writeBodyTooLarge(w http.ResponseWriter, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return false
+	}
+
+	response.Error(w, http.StatusRequestEntityTooLarge, response.CodePayloadTooLarge,
+		fmt.Sprintf("Тело запроса не должно превышать %d байт", maxBytesErr.Limit))
+	return true
+}
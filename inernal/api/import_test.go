@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// importCSV — загружает content как CSV-файл в multipart-поле file через POST /users/import
+func (s *testServer) importCSV(t *testing.T, content string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile(importFileField, "users.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return s.do(t, http.MethodPost, APIPrefix+"/users/import", body.String(), "Content-Type", form.FormDataContentType())
+}
+
+func TestImportUsersCSV(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	rec := s.importCSV(t, "username,email\nalice,Alice@Example.com\nbob,bob@example.com\n")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	var summary importSummary
+	decodeData(t, rec, &summary)
+	if summary.Imported != 2 || len(summary.Failed) != 0 {
+		t.Fatalf("итог импорта %+v, ожидались 2 пользователя без ошибок", summary)
+	}
+
+	// Email нормализуется так же, как при создании через API
+	users := s.listUsers(t, "?sort=username")
+	if !slices.Equal(usernames(users), []string{"alice", "bob"}) || users[0].Email != "alice@example.com" {
+		t.Fatalf("после импорта в списке %+v", users)
+	}
+}
+
+func TestImportUsersCSVInvalidRows(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	rec := s.importCSV(t, "username,email\n"+
+		"alice,alice@example.com\n"+
+		"ab,bob@example.com\n"+
+		"carol,notanemail\n"+
+		"dave\n"+
+		"erin,erin@example.com\n")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	var summary importSummary
+	decodeData(t, rec, &summary)
+	if summary.Imported != 2 {
+		t.Fatalf("импортировано %d, ожидалось 2", summary.Imported)
+	}
+
+	// Номера строк считаются от заголовка, причина названа для каждой
+	want := []importRowError{{Row: 3, Field: "username"}, {Row: 4, Field: "email"}, {Row: 5}}
+	if len(summary.Failed) != len(want) {
+		t.Fatalf("отклонены строки %+v, ожидались 3, 4 и 5", summary.Failed)
+	}
+	for i, failed := range summary.Failed {
+		if failed.Row != want[i].Row || failed.Field != want[i].Field || failed.Message == "" {
+			t.Fatalf("ошибка %d: %+v, ожидалась строка %d, поле %q", i, failed, want[i].Row, want[i].Field)
+		}
+	}
+	if names := usernames(s.listUsers(t, "?sort=username")); !slices.Equal(names, []string{"alice", "erin"}) {
+		t.Fatalf("сохранены %v, ожидались alice и erin", names)
+	}
+}
+
+func TestImportUsersCSVRejected(t *testing.T) {
+	opts := testOptions()
+	opts.MaxBodyBytes = 1024
+	s := newTestServer(t, opts)
+
+	// Заголовок не совпадает с ожидаемыми столбцами: файл отклоняется целиком
+	rec := s.importCSV(t, "email,username\nalice@example.com,alice\n")
+	apiErr := expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+	if !strings.Contains(apiErr.Message, "username,email") {
+		t.Fatalf("сообщение %q не называет ожидаемый заголовок", apiErr.Message)
+	}
+
+	rec = s.importCSV(t, "")
+	expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+
+	// Файл больше MaxBodyBytes
+	rec = s.importCSV(t, "username,email\n"+strings.Repeat("alice,alice@example.com\n", 100))
+	expectError(t, rec, http.StatusRequestEntityTooLarge, response.CodePayloadTooLarge)
+
+	// Тело без multipart
+	rec = s.do(t, http.MethodPost, APIPrefix+"/users/import", "username,email\n", "Content-Type", "text/csv")
+	expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+
+	if count := s.countUsers(t); count != 0 {
+		t.Fatalf("из отклонённых файлов сохранено %d пользователей", count)
+	}
+}
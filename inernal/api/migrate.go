@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/migrator"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// AdminMigratePath — путь запуска миграций администратором. Миграции идут дольше обычного запроса,
+// поэтому на этот путь не распространяется общий дедлайн
+const AdminMigratePath = "/admin/migrate"
+
+// MigrationRunner — мигратор, умеющий применить ожидающие миграции по запросу администратора
+type MigrationRunner interface {
+	Apply() ([]migrator.AppliedMigration, error)
+	Version() (int64, error)
+}
+
+// appliedMigration — миграция в ответе POST /admin/migrate
+type appliedMigration struct {
+	Version  int64  `json:"version"`
+	Name     string `json:"name"`
+	Duration string `json:"duration"`
+}
+
+// migrateResult — ответ POST /admin/migrate: применённые миграции и итоговая версия схемы
+type migrateResult struct {
+	Applied       []appliedMigration `json:"applied"`
+	SchemaVersion int64              `json:"schema_version"`
+}
+
+// migrateHandler — обработчик POST-запросов для применения ожидающих миграций.
+// Одновременные запуски с разных экземпляров безопасны: мигратор применяет миграции под advisory-блокировкой,
+// и второй запуск дождётся первого, а затем найдёт схему актуальной
+func (h *Handler) migrateHandler(w http.ResponseWriter, r *http.Request) {
+	runner, ok := h.migrations.(MigrationRunner)
+	if !ok {
+		requestLogger(r).Error("Мигратор не поддерживает запуск миграций по запросу")
+		response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Запуск миграций не поддерживается")
+		return
+	}
+
+	// Миграция может идти дольше WriteTimeout сервера, поэтому снимаем дедлайн записи для этого соединения
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		requestLogger(r).Warn("Не удалось снять дедлайн записи для запуска миграций", "error", err)
+	}
+
+	// Мигратор не принимает контекст: начатую миграцию не прерываем, даже если клиент отключился
+	requestLogger(r).Info("Запуск миграций по запросу администратора")
+	applied, err := runner.Apply()
+	if err != nil {
+		requestLogger(r).Error("Ошибка применения миграций", "error", err)
+		if errors.Is(err, migrator.ErrLockTimeout) {
+			response.Error(w, http.StatusServiceUnavailable, response.CodeUnavailable, "Миграции выполняет другой экземпляр, повторите позже")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Ошибка применения миграций: "+err.Error())
+		return
+	}
+
+	version, err := runner.Version()
+	if err != nil {
+		requestLogger(r).Error("Ошибка получения версии схемы", "error", err)
+		response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Ошибка получения версии схемы")
+		return
+	}
+
+	result := migrateResult{Applied: make([]appliedMigration, 0, len(applied)), SchemaVersion: version}
+	for _, migration := range applied {
+		result.Applied = append(result.Applied, appliedMigration{
+			Version:  migration.Version,
+			Name:     migration.Name,
+			Duration: migration.Duration.String(),
+		})
+	}
+
+	requestLogger(r).Info("Миграции применены по запросу администратора", "applied", len(applied), "schema_version", version)
+	response.Data(w, http.StatusOK, result, nil)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/migrator"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// testAdminToken — токен администратора для тестов административных маршрутов
+const testAdminToken = "admin-token-0123456789abcdef012345"
+
+// fakeRunner — мигратор, применяющий заданные миграции или возвращающий err
+type fakeRunner struct {
+	pending []migrator.AppliedMigration
+	version int64
+	err     error
+}
+
+func (r *fakeRunner) PendingVersions(context.Context) ([]int64, error) {
+	versions := make([]int64, 0, len(r.pending))
+	for _, migration := range r.pending {
+		versions = append(versions, migration.Version)
+	}
+
+	return versions, nil
+}
+
+func (r *fakeRunner) Apply() ([]migrator.AppliedMigration, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	applied := r.pending
+	r.pending = nil
+	if len(applied) > 0 {
+		r.version = applied[len(applied)-1].Version
+	}
+
+	return applied, nil
+}
+
+func (r *fakeRunner) Version() (int64, error) {
+	return r.version, nil
+}
+
+// newMigrateServer — тестовый сервер с мигратором runner и заданным флагом ADMIN_MIGRATE_ENABLED
+func newMigrateServer(runner *fakeRunner, enabled bool) *testServer {
+	opts := testOptions()
+	opts.AdminToken = testAdminToken
+	opts.AdminMigrateEnabled = enabled
+	handler := NewHandler(repository.NewMemoryUserRepository(), nil, nil, nil, runner, opts)
+	mux := http.NewServeMux()
+	handler.Register(mux)
+
+	return &testServer{handler: handler, mux: WithJSONFallback(mux)}
+}
+
+func TestAdminMigrate(t *testing.T) {
+	runner := &fakeRunner{version: 2, pending: []migrator.AppliedMigration{
+		{Version: 3, Name: "00003_add_users_email_index.sql", Duration: 15 * time.Millisecond},
+		{Version: 4, Name: "00004_add_users_deleted_at.sql", Duration: 2 * time.Millisecond},
+	}}
+	s := newMigrateServer(runner, true)
+
+	rec := s.do(t, http.MethodPost, AdminMigratePath, "", AdminTokenHeader, testAdminToken)
+	var result migrateResult
+	decodeData(t, rec, &result)
+	if rec.Code != http.StatusOK || result.SchemaVersion != 4 || len(result.Applied) != 2 {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	if got := result.Applied[0]; got.Version != 3 || got.Name != "00003_add_users_email_index.sql" || got.Duration != "15ms" {
+		t.Fatalf("первая миграция в ответе %+v", got)
+	}
+
+	// Повторный запуск: применять нечего, версия прежняя, applied — пустой массив, а не null
+	rec = s.do(t, http.MethodPost, AdminMigratePath, "", AdminTokenHeader, testAdminToken)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"applied":[]`) {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+
+	// Без токена администратора миграции не запускаются
+	rec = s.do(t, http.MethodPost, AdminMigratePath, "")
+	expectError(t, rec, http.StatusUnauthorized, response.CodeUnauthorized)
+}
+
+func TestAdminMigrateErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{err: fmt.Errorf("ожидание блокировки: %w", migrator.ErrLockTimeout), status: http.StatusServiceUnavailable, code: response.CodeUnavailable},
+		{err: errors.New("syntax error at or near"), status: http.StatusInternalServerError, code: response.CodeInternal},
+	}
+	for _, tt := range tests {
+		s := newMigrateServer(&fakeRunner{err: tt.err}, true)
+
+		rec := s.do(t, http.MethodPost, AdminMigratePath, "", AdminTokenHeader, testAdminToken)
+		expectError(t, rec, tt.status, tt.code)
+	}
+}
+
+func TestAdminMigrateDisabled(t *testing.T) {
+	runner := &fakeRunner{pending: []migrator.AppliedMigration{{Version: 1, Name: "00001_create_users.sql"}}}
+	s := newMigrateServer(runner, false)
+
+	// Без ADMIN_MIGRATE_ENABLED маршрута нет даже для администратора
+	rec := s.do(t, http.MethodPost, AdminMigratePath, "", AdminTokenHeader, testAdminToken)
+	expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+	if len(runner.pending) != 1 {
+		t.Fatal("миграции применены при выключенном маршруте")
+	}
+}
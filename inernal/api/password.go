@@ -0,0 +1,46 @@
+package api
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+const (
+	// minPasswordLength — минимальная длина пароля в символах
+	minPasswordLength = 8
+	// maxPasswordBytes — bcrypt учитывает только первые 72 байта пароля, более длинные отклоняем
+	maxPasswordBytes = 72
+)
+
+// validatePassword — проверяет длину пароля
+func validatePassword(password string) *FieldError {
+	if len([]rune(password)) < minPasswordLength {
+		return &FieldError{Field: "password", Message: fmt.Sprintf("Пароль должен быть не короче %d символов", minPasswordLength)}
+	}
+	if len(password) > maxPasswordBytes {
+		return &FieldError{Field: "password", Message: fmt.Sprintf("Пароль не должен быть длиннее %d байт", maxPasswordBytes)}
+	}
+
+	return nil
+}
+
+// hashPassword — заменяет пароль пользователя его bcrypt-хешем и стирает открытый текст,
+// чтобы он не попал ни в базу, ни в ответ. Пустой пароль оставляет хеш пустым
+func hashPassword(user *model.User, cost int) error {
+	if user.Password == "" {
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), cost)
+	if err != nil {
+		return fmt.Errorf("не удалось захешировать пароль: %w", err)
+	}
+
+	user.PasswordHash = string(hash)
+	user.Password = ""
+
+	return nil
+}
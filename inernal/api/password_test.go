@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+func TestCreateUserHashesPassword(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	body := `{"username":"alice","email":"alice@example.com","password":"correct horse battery"}`
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	// Ни пароль, ни хеш не возвращаются клиенту
+	if strings.Contains(rec.Body.String(), "correct horse battery") || strings.Contains(rec.Body.String(), "password") {
+		t.Fatalf("ответ содержит пароль или его хеш: %s", rec.Body)
+	}
+
+	var created model.User
+	decodeData(t, rec, &created)
+	stored, err := s.users.GetByID(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password != "" {
+		t.Fatal("в хранилище попал пароль в открытом виде")
+	}
+	if cost, err := bcrypt.Cost([]byte(stored.PasswordHash)); err != nil || cost != bcrypt.MinCost {
+		t.Fatalf("сохранено %q: не bcrypt-хеш с заданной стоимостью (cost=%d, err=%v)", stored.PasswordHash, cost, err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte("correct horse battery")); err != nil {
+		t.Fatalf("хеш не соответствует паролю: %v", err)
+	}
+
+	// GET тоже не раскрывает хеш
+	rec = s.do(t, http.MethodGet, APIPrefix+"/users/"+created.ID.String(), "")
+	if strings.Contains(rec.Body.String(), stored.PasswordHash) || strings.Contains(rec.Body.String(), "password") {
+		t.Fatalf("GET раскрывает хеш пароля: %s", rec.Body)
+	}
+}
+
+func TestCreateUserPasswordValidation(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	for name, password := range map[string]string{
+		"без пароля":      `"password":""`,
+		"короткий":        `"password":"1234567"`,
+		"длиннее 72 байт": `"password":"` + strings.Repeat("x", maxPasswordBytes+1) + `"`,
+	} {
+		body := `{"username":"alice","email":"alice@example.com",` + password + `}`
+		rec := s.do(t, http.MethodPost, APIPrefix+"/users", body)
+		apiErr := expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+
+		var fields []FieldError
+		if err := json.Unmarshal(apiErr.Details, &fields); err != nil {
+			t.Fatal(err)
+		}
+		if len(fields) != 1 || fields[0].Field != "password" {
+			t.Fatalf("%s: details = %+v, ожидалась ошибка поля password", name, fields)
+		}
+	}
+}
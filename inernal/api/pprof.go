@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// PprofHandler — обработчик профилировщика для отдельного служебного порта. Доступные профили:
+//   - /debug/pprof/ — список профилей и счётчики
+//   - /debug/pprof/profile?seconds=N — CPU-профиль за N секунд (по умолчанию 30)
+//   - /debug/pprof/heap и /debug/pprof/allocs — живые объекты и все выделения памяти
+//   - /debug/pprof/goroutine, /debug/pprof/block, /debug/pprof/mutex, /debug/pprof/threadcreate
+//   - /debug/pprof/trace?seconds=N — трасса выполнения для go tool trace
+//
+// Регистрируется на собственном mux, а не на основном, поэтому публичный порт профили не отдаёт
+func PprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	// Index сам отдаёт именованные профили (heap, goroutine и т.д.) по пути /debug/pprof/<имя>
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+
+	return mux
+}
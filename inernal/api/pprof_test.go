@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+func TestPprofNotOnMainMux(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	// На основном mux профилировщика нет при любых настройках: он живёт только на служебном порту
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/profile"} {
+		rec := s.do(t, http.MethodGet, path, "")
+		expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+	}
+}
+
+func TestPprofHandler(t *testing.T) {
+	handler := PprofHandler()
+
+	for path, want := range map[string]string{
+		"/debug/pprof/":                  "heap",
+		"/debug/pprof/heap?debug=1":      "heap profile",
+		"/debug/pprof/goroutine?debug=1": "goroutine profile",
+		"/debug/pprof/cmdline":           "",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("%s: статус %d, тело без %q", path, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+const (
+	// streamKeepAlive — период комментариев-пингов, чтобы прокси не закрывали простаивающее соединение
+	streamKeepAlive = 15 * time.Second
+	// streamBuffer — сколько событий копится для медленного подписчика, прежде чем новые начнут теряться
+	streamBuffer = 64
+)
+
+// userFeed — рассылка событий о новых пользователях внутри процесса всем подписчикам потока.
+// Публикация не блокируется: медленный подписчик теряет события, но не задерживает создание пользователей
+type userFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan model.User]struct{}
+}
+
+// newUserFeed создаёт рассылку без подписчиков
+func newUserFeed() *userFeed {
+	return &userFeed{subscribers: make(map[chan model.User]struct{})}
+}
+
+// subscribe — добавляет подписчика; возвращённую функцию нужно вызвать, чтобы отписаться
+func (f *userFeed) subscribe() (<-chan model.User, func()) {
+	ch := make(chan model.User, streamBuffer)
+
+	f.mu.Lock()
+	f.subscribers[ch] = struct{}{}
+	f.mu.Unlock()
+
+	return ch, func() {
+		f.mu.Lock()
+		delete(f.subscribers, ch)
+		f.mu.Unlock()
+	}
+}
+
+// publish — отправляет пользователя всем подписчикам, пропуская тех, чей буфер заполнен
+func (f *userFeed) publish(user model.User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- user:
+		default:
+		}
+	}
+}
+
+// streamUsersHandler — поток Server-Sent Events о новых пользователях для живой ленты регистраций.
+// Соединение держится, пока клиент не отключится; подписка снимается по отмене контекста запроса
+func (h *Handler) streamUsersHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// Поток живёт дольше WriteTimeout сервера, поэтому снимаем дедлайн записи для этого соединения
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		requestLogger(r).Error("Не удалось снять дедлайн записи для потока", "error", err)
+		response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Потоковая передача не поддерживается")
+		return
+	}
+
+	events, unsubscribe := h.feed.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Запрещаем буферизацию в nginx, иначе события будут приходить пачками
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		requestLogger(r).Warn("Не удалось начать поток событий", "error", err)
+		return
+	}
+
+	requestLogger(r).Info("Клиент подписался на поток пользователей")
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			requestLogger(r).Info("Клиент отключился от потока пользователей")
+			return
+		case <-keepAlive.C:
+			// Строка-комментарий игнорируется клиентом, но сбрасывает таймеры простоя у прокси
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case user := <-events:
+			data, err := json.Marshal(user)
+			if err != nil {
+				requestLogger(r).Error("Ошибка сериализации события", "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: user.created\nid: %s\ndata: %s\n\n", user.ID, data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+// subscriberCount — число текущих подписчиков рассылки
+func subscriberCount(f *userFeed) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.subscribers)
+}
+
+func TestStreamUsers(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	server := httptest.NewServer(s.mux)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+APIPrefix+"/users/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("статус %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// Заголовки приходят после подписки, поэтому созданный теперь пользователь попадёт в поток
+	created := s.createUser(t, "alice", "alice@example.com")
+
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && scanner.Text() != "" {
+		name, value, _ := strings.Cut(scanner.Text(), ": ")
+		fields[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("чтение потока: %v", err)
+	}
+
+	var user model.User
+	if err := json.Unmarshal([]byte(fields["data"]), &user); err != nil {
+		t.Fatalf("data события %q: %v", fields["data"], err)
+	}
+	if fields["event"] != "user.created" || fields["id"] != created.ID.String() || user.Username != "alice" {
+		t.Fatalf("получено событие %v, ожидался user.created для %s", fields, created.ID)
+	}
+}
+
+func TestStreamUsersUnsubscribesOnDisconnect(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	server := httptest.NewServer(s.mux)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+APIPrefix+"/users/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subscribers := subscriberCount(s.handler.feed); subscribers != 1 {
+		t.Fatalf("подписчиков %d, ожидался один", subscribers)
+	}
+
+	// Клиент отключился: обработчик видит отмену контекста и снимает подписку
+	cancel()
+	_ = resp.Body.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for subscriberCount(s.handler.feed) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("подписка не снята после отключения клиента")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
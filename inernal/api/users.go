@@ -0,0 +1,553 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+const (
+	// defaultListLimit — число пользователей на странице, если limit не указан
+	defaultListLimit = 50
+	// maxListLimit — максимально допустимый размер страницы; большие значения урезаются до него
+	maxListLimit = 200
+	// maxSearchQueryLength — ограничение длины поисковой строки ?q=, чтобы не строить дорогие запросы
+	maxSearchQueryLength = 100
+)
+
+// createUserHandler — обработчик POST-запросов для создания нового пользователя.
+// Массив пользователей в теле создаётся пакетом, как в POST /users/batch, и в ответе тоже приходит массив
+func (h *Handler) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	if h.peekJSONArray(w, r) {
+		h.createUsersBatchHandler(w, r)
+		return
+	}
+
+	var user model.User
+	// Парсим JSON-тело запроса в структуру User
+	if !h.decodeJSON(w, r, &user) {
+		return
+	}
+
+	// Проверяем обязательные поля и приводим email к каноничному виду
+	if validationErr := validateNewUser(&user); validationErr != nil {
+		writeValidationError(w, r, validationErr)
+		return
+	}
+	if h.rejectDisposableEmail(w, r, user.Email) {
+		return
+	}
+
+	// В базу попадает только хеш пароля
+	if err := hashPassword(&user, h.opts.BcryptCost); err != nil {
+		requestLogger(r).Error("Ошибка хеширования пароля", "error", err)
+		response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Ошибка сервера")
+		return
+	}
+
+	// Хеширование заметно по времени: если клиент за это время отключился, вставку не выполняем
+	if clientGone(r) {
+		return
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	if err := h.users.Create(ctx, &user); err != nil {
+		// Отдельно обрабатываем попытку зарегистрировать уже занятые email или имя
+		if writeConflict(w, r, err) {
+			return
+		}
+
+		writeDBError(w, r, "Ошибка вставки в базу", err)
+		return
+	}
+
+	requestLogger(r).Info("Пользователь создан", "user_id", user.ID)
+	h.userCreated(user)
+
+	// Возвращаем созданного пользователя и ссылку на него
+	w.Header().Set("Location", fmt.Sprintf("%s/users/%s", APIPrefix, user.ID))
+	response.Data(w, http.StatusCreated, user, nil)
+}
+
+// listUsersHandler — обработчик GET-запросов для получения списка пользователей
+func (h *Handler) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	// Параметры пагинации: ?limit=...&offset=...
+	limit, err := parseNonNegativeQueryInt(r, "limit", defaultListLimit)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный параметр пагинации", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery, err.Error())
+		return
+	}
+	limit = min(limit, maxListLimit)
+
+	offset, err := parseNonNegativeQueryInt(r, "offset", 0)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный параметр пагинации", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery, err.Error())
+		return
+	}
+
+	// Порядок сортировки: ?sort=username, ?sort=-created_at и т.п.
+	sort, err := parseSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		requestLogger(r).Warn("Некорректный параметр сортировки", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery, err.Error())
+		return
+	}
+
+	filter, err := parseListFilter(r)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный параметр фильтра", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery, err.Error())
+		return
+	}
+
+	// Курсорная пагинация: ?cursor=... из X-Next-Cursor предыдущей страницы.
+	// Курсор заменяет offset, поэтому одновременно их передавать нельзя
+	var after *repository.Cursor
+	if value := r.URL.Query().Get("cursor"); value != "" {
+		if r.URL.Query().Has("offset") {
+			requestLogger(r).Warn("Одновременно переданы cursor и offset")
+			response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery, "параметры cursor и offset нельзя использовать вместе")
+			return
+		}
+
+		after, err = decodeCursor(value, sort)
+		if err != nil {
+			requestLogger(r).Warn("Некорректный курсор", "error", err)
+			response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery, err.Error())
+			return
+		}
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	// Общее число пользователей нужно клиенту для построения пагинации
+	total, err := h.users.Count(ctx, filter)
+	if err != nil {
+		writeDBError(w, r, "Ошибка подсчёта пользователей", err)
+		return
+	}
+
+	// Запрашиваем на одну запись больше: по ней понятно, есть ли следующая страница
+	users, err := h.users.List(ctx, repository.ListParams{
+		ListFilter: filter,
+		Limit:      limit + 1,
+		Offset:     offset,
+		Sort:       sort,
+		After:      after,
+	})
+	if err != nil {
+		writeDBError(w, r, "Ошибка выборки из базы", err)
+		return
+	}
+
+	meta := listMeta{Total: total, Limit: limit, Offset: offset}
+	// Курсор следующей страницы отдаём, только если записи ещё остались
+	if len(users) > limit {
+		users = users[:limit]
+		if limit > 0 {
+			meta.NextCursor = encodeCursor(sort, users[len(users)-1])
+			w.Header().Set("X-Next-Cursor", meta.NextCursor)
+		}
+	}
+
+	requestLogger(r).Debug("Список пользователей получен", "count", len(users))
+
+	// Возвращаем список пользователей в формате JSON; заголовки дублируют meta для старых клиентов
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	response.Data(w, http.StatusOK, users, meta)
+}
+
+// listMeta — сведения о пагинации в meta ответа со списком пользователей
+type listMeta struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// parseListFilter — разбирает параметры фильтрации, общие для списка и подсчёта пользователей,
+// чтобы число из /users/count совпадало с X-Total-Count списка при тех же параметрах
+func parseListFilter(r *http.Request) (repository.ListFilter, error) {
+	// Мягко удалённых пользователей учитываем только по явному запросу: ?include_deleted=true
+	includeDeleted, err := parseBoolQuery(r, "include_deleted")
+	if err != nil {
+		return repository.ListFilter{}, err
+	}
+
+	// Поиск по части имени пользователя без учёта регистра: ?q=...
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if utf8.RuneCountInString(query) > maxSearchQueryLength {
+		return repository.ListFilter{}, fmt.Errorf("параметр q не должен быть длиннее %d символов", maxSearchQueryLength)
+	}
+
+	// Точный поиск по email: ?email=... Приводим к нижнему регистру, как при сохранении,
+	// поэтому регистр в запросе не важен
+	email := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("email")))
+
+	return repository.ListFilter{
+		IncludeDeleted: includeDeleted,
+		UsernameQuery:  query,
+		Email:          email,
+	}, nil
+}
+
+// countUsersHandler — обработчик GET-запросов для подсчёта пользователей без выборки самих записей
+func (h *Handler) countUsersHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный параметр фильтра", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery, err.Error())
+		return
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	count, err := h.users.Count(ctx, filter)
+	if err != nil {
+		writeDBError(w, r, "Ошибка подсчёта пользователей", err)
+		return
+	}
+
+	response.Data(w, http.StatusOK, map[string]int64{"count": count}, nil)
+}
+
+// conflictDetails — поле, на котором произошёл конфликт уникальности
+type conflictDetails struct {
+	Field string `json:"field"`
+}
+
+// writeConflict — отвечает 409, если err означает занятый email или имя пользователя, и сообщает,
+// какое поле совпало. Возвращает false, если err не конфликт уникальности
+func writeConflict(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, repository.ErrEmailTaken):
+		requestLogger(r).Warn("Пользователь с таким email уже существует")
+		response.ErrorWithDetails(w, http.StatusConflict, response.CodeEmailTaken,
+			"Пользователь с таким email уже существует", conflictDetails{Field: "email"})
+	case errors.Is(err, repository.ErrUsernameTaken):
+		requestLogger(r).Warn("Пользователь с таким именем уже существует")
+		response.ErrorWithDetails(w, http.StatusConflict, response.CodeUsernameTaken,
+			"Пользователь с таким именем уже существует", conflictDetails{Field: "username"})
+	default:
+		return false
+	}
+
+	return true
+}
+
+// sortableFields — поля, по которым разрешено сортировать список; всё остальное отклоняется
+var sortableFields = map[string]repository.SortField{
+	"created_at": repository.SortByCreatedAt,
+	"username":   repository.SortByUsername,
+}
+
+// parseSort — разбирает параметр sort: имя поля из sortableFields, минус в начале означает обратный порядок.
+// Без параметра список сортируется от новых пользователей к старым
+func parseSort(value string) (repository.Sort, error) {
+	if value == "" {
+		return repository.Sort{Field: repository.SortByCreatedAt, Desc: true}, nil
+	}
+
+	name, desc := strings.CutPrefix(value, "-")
+	field, ok := sortableFields[name]
+	if !ok {
+		return repository.Sort{}, errors.New("параметр sort должен быть одним из: created_at, -created_at, username, -username")
+	}
+
+	return repository.Sort{Field: field, Desc: desc}, nil
+}
+
+// getUserHandler — обработчик GET-запросов для получения одного пользователя по идентификатору.
+// Маршрут GET в ServeMux обслуживает и HEAD: так клиенты проверяют, существует ли пользователь, без тела ответа
+func (h *Handler) getUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUserID(r)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный идентификатор пользователя", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidID, "Некорректный идентификатор пользователя")
+		return
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	user, err := h.users.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			requestLogger(r).Warn("Пользователь не найден", "user_id", id)
+			response.Error(w, http.StatusNotFound, response.CodeNotFound, "Пользователь не найден")
+			return
+		}
+
+		writeDBError(w, r, "Ошибка выборки из базы", err)
+		return
+	}
+
+	// Для HEAD тело отбрасывается, поэтому его не отправляем, а только считаем длину для Content-Length.
+	// Ошибки выше отвечают с теми же статусом и заголовками, что и на GET
+	if r.Method == http.MethodHead {
+		response.DataHead(w, http.StatusOK, user, nil)
+		return
+	}
+
+	// Возвращаем найденного пользователя в формате JSON
+	response.Data(w, http.StatusOK, user, nil)
+}
+
+// getUserByEmailHandler — обработчик GET-запросов для поиска пользователя по email.
+// ServeMux уже раскодировал путь, поэтому %2B приходит как +; сам + в пути остаётся плюсом, а не пробелом
+func (h *Handler) getUserByEmailHandler(w http.ResponseWriter, r *http.Request) {
+	// Приводим email к тому виду, в котором он хранится, — так поиск не зависит от регистра
+	email, err := normalizeEmail(r.PathValue("email"))
+	if err != nil {
+		writeValidationError(w, r, &ValidationError{Fields: []FieldError{{Field: "email", Message: "Некорректный формат email"}}})
+		return
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	// Email уникален среди активных пользователей, поэтому достаточно одной строки
+	users, err := h.users.List(ctx, repository.ListParams{ListFilter: repository.ListFilter{Email: email}, Limit: 1})
+	if err != nil {
+		writeDBError(w, r, "Ошибка выборки из базы", err)
+		return
+	}
+	if len(users) == 0 {
+		requestLogger(r).Warn("Пользователь не найден", "email", email)
+		response.Error(w, http.StatusNotFound, response.CodeNotFound, "Пользователь не найден")
+		return
+	}
+
+	response.Data(w, http.StatusOK, users[0], nil)
+}
+
+// updateUserHandler — обработчик PUT-запросов для полной замены данных пользователя
+func (h *Handler) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUserID(r)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный идентификатор пользователя", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidID, "Некорректный идентификатор пользователя")
+		return
+	}
+
+	var user model.User
+	// Парсим JSON-тело запроса в структуру User
+	if !h.decodeJSON(w, r, &user) {
+		return
+	}
+
+	// Применяем те же правила проверки, что и при создании пользователя
+	if validationErr := validateUser(&user); validationErr != nil {
+		writeValidationError(w, r, validationErr)
+		return
+	}
+	if h.rejectDisposableEmail(w, r, user.Email) {
+		return
+	}
+	user.ID = id
+
+	// Новый пароль необязателен; если он передан, в базу попадает только его хеш
+	if err := hashPassword(&user, h.opts.BcryptCost); err != nil {
+		requestLogger(r).Error("Ошибка хеширования пароля", "error", err)
+		response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Ошибка сервера")
+		return
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	if err := h.users.Update(ctx, &user); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			requestLogger(r).Warn("Пользователь не найден", "user_id", id)
+			response.Error(w, http.StatusNotFound, response.CodeNotFound, "Пользователь не найден")
+			return
+		}
+
+		// Новый email или имя уже заняты другим пользователем
+		if writeConflict(w, r, err) {
+			return
+		}
+
+		writeDBError(w, r, "Ошибка обновления в базе", err)
+		return
+	}
+
+	requestLogger(r).Info("Пользователь обновлён", "user_id", user.ID)
+
+	// Возвращаем обновлённого пользователя в формате JSON
+	response.Data(w, http.StatusOK, user, nil)
+}
+
+// patchUserRequest — тело PATCH-запроса. Указатели отличают отсутствующее поле от пустого значения
+type patchUserRequest struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+}
+
+// patchUserHandler — обработчик PATCH-запросов для частичного обновления пользователя:
+// меняются только поля, переданные в теле
+func (h *Handler) patchUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUserID(r)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный идентификатор пользователя", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidID, "Некорректный идентификатор пользователя")
+		return
+	}
+
+	var req patchUserRequest
+	// Парсим JSON-тело запроса; пустое тело отклоняется как некорректный JSON
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	patch := repository.UserPatch{Username: req.Username, Email: req.Email}
+	if validationErr := validatePatch(&patch); validationErr != nil {
+		writeValidationError(w, r, validationErr)
+		return
+	}
+	// Email проверяем, только если его меняют: переименование не должно упираться в старый адрес
+	if patch.Email != nil && h.rejectDisposableEmail(w, r, *patch.Email) {
+		return
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	user, err := h.users.Patch(ctx, id, patch)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			requestLogger(r).Warn("Пользователь не найден", "user_id", id)
+			response.Error(w, http.StatusNotFound, response.CodeNotFound, "Пользователь не найден")
+			return
+		}
+
+		// Новый email или имя уже заняты другим пользователем
+		if writeConflict(w, r, err) {
+			return
+		}
+
+		writeDBError(w, r, "Ошибка обновления в базе", err)
+		return
+	}
+
+	requestLogger(r).Info("Пользователь частично обновлён", "user_id", user.ID)
+
+	// Возвращаем обновлённого пользователя в формате JSON
+	response.Data(w, http.StatusOK, user, nil)
+}
+
+// deleteUserHandler — обработчик DELETE-запросов для мягкого удаления пользователя по идентификатору
+func (h *Handler) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUserID(r)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный идентификатор пользователя", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidID, "Некорректный идентификатор пользователя")
+		return
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	if err := h.users.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			requestLogger(r).Warn("Пользователь не найден", "user_id", id)
+			response.Error(w, http.StatusNotFound, response.CodeNotFound, "Пользователь не найден")
+			return
+		}
+
+		writeDBError(w, r, "Ошибка удаления из базы", err)
+		return
+	}
+
+	requestLogger(r).Info("Пользователь удалён", "user_id", id)
+
+	// Успешное удаление — ответ без тела
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// restoreUserHandler — обработчик POST-запросов для восстановления мягко удалённого пользователя.
+// Восстановить не удалённого пользователя или пользователя, чей email или имя уже заняты, нельзя — 409
+func (h *Handler) restoreUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUserID(r)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный идентификатор пользователя", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidID, "Некорректный идентификатор пользователя")
+		return
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	user, err := h.users.Restore(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			requestLogger(r).Warn("Пользователь не найден", "user_id", id)
+			response.Error(w, http.StatusNotFound, response.CodeNotFound, "Пользователь не найден")
+		case errors.Is(err, repository.ErrNotDeleted):
+			requestLogger(r).Warn("Пользователь не удалён, восстанавливать нечего", "user_id", id)
+			response.Error(w, http.StatusConflict, response.CodeNotDeleted, "Пользователь не удалён")
+		case writeConflict(w, r, err):
+		default:
+			writeDBError(w, r, "Ошибка восстановления пользователя", err)
+		}
+		return
+	}
+
+	requestLogger(r).Info("Пользователь восстановлен", "user_id", id)
+	response.Data(w, http.StatusOK, user, nil)
+}
+
+// deleteUsersHandler — обработчик DELETE-запросов для мягкого удаления всех пользователей под фильтром.
+// Фильтр ?q= или ?email= обязателен, чтобы случайный запрос без параметров не удалил всю таблицу
+func (h *Handler) deleteUsersHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r)
+	if err != nil {
+		requestLogger(r).Warn("Некорректный параметр фильтра", "error", err)
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery, err.Error())
+		return
+	}
+	if filter.UsernameQuery == "" && filter.Email == "" {
+		requestLogger(r).Warn("Массовое удаление без фильтра отклонено")
+		response.Error(w, http.StatusBadRequest, response.CodeInvalidQuery,
+			"Для массового удаления нужен фильтр: параметр q или email")
+		return
+	}
+
+	// Контекст с таймаутом для выполнения запроса к базе
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.DBOpTimeout)
+	defer cancel()
+
+	ids, err := h.users.DeleteMatching(ctx, filter)
+	if err != nil {
+		writeDBError(w, r, "Ошибка массового удаления пользователей", err)
+		return
+	}
+
+	requestLogger(r).Warn("Пользователи удалены по фильтру",
+		"q", filter.UsernameQuery, "email", filter.Email, "deleted", len(ids))
+	response.Data(w, http.StatusOK, map[string]int{"deleted": len(ids)}, nil)
+}
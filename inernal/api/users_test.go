@@ -0,0 +1,643 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+func TestGetUser(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	created := s.createUser(t, "alice", "alice@example.com")
+
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users/"+created.ID.String(), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("существующий пользователь: статус %d, тело %s", rec.Code, rec.Body)
+	}
+	var got model.User
+	decodeData(t, rec, &got)
+	if got.ID != created.ID || got.Username != "alice" || got.Email != "alice@example.com" {
+		t.Fatalf("получен %+v, ожидался %+v", got, created)
+	}
+
+	rec = s.do(t, http.MethodGet, APIPrefix+"/users/"+uuid.NewString(), "")
+	expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+
+	for _, id := range []string{"42", "not-a-uuid", created.ID.String() + "0"} {
+		rec = s.do(t, http.MethodGet, APIPrefix+"/users/"+id, "")
+		expectError(t, rec, http.StatusBadRequest, response.CodeInvalidID)
+	}
+}
+
+func TestHeadUser(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+
+	// Настоящий сервер, а не ResponseRecorder: тело ответа на HEAD отбрасывает именно net/http
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+
+	get := s.do(t, http.MethodGet, APIPrefix+"/users/"+user.ID.String(), "")
+
+	for _, path := range []string{APIPrefix + "/users/", "/users/"} {
+		t.Run(path, func(t *testing.T) {
+			resp, body := head(t, server.URL+path+user.ID.String())
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("существующий пользователь: статус %d, ожидался 200", resp.StatusCode)
+			}
+			if len(body) != 0 {
+				t.Fatalf("у ответа на HEAD есть тело: %q", body)
+			}
+			// Заголовки совпадают с GET, включая длину тела, которое вернул бы GET
+			if got, want := resp.Header.Get("Content-Length"), strconv.Itoa(get.Body.Len()); got != want {
+				t.Fatalf("Content-Length %s, у GET %s", got, want)
+			}
+			if got, want := resp.Header.Get("Content-Type"), get.Header().Get("Content-Type"); got != want {
+				t.Fatalf("Content-Type %q, у GET %q", got, want)
+			}
+
+			resp, body = head(t, server.URL+path+uuid.NewString())
+			if resp.StatusCode != http.StatusNotFound {
+				t.Fatalf("несуществующий пользователь: статус %d, ожидался 404", resp.StatusCode)
+			}
+			if len(body) != 0 {
+				t.Fatalf("у ответа на HEAD есть тело: %q", body)
+			}
+		})
+	}
+}
+
+// head — отправляет HEAD-запрос и возвращает ответ вместе с прочитанным телом
+func head(t *testing.T, url string) (*http.Response, []byte) {
+	t.Helper()
+
+	resp, err := http.Head(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp, body
+}
+
+func TestDeleteUser(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+	path := APIPrefix + "/users/" + user.ID.String()
+
+	rec := s.do(t, http.MethodDelete, path, "")
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Fatalf("удаление: статус %d, тело %q; ожидался 204 без тела", rec.Code, rec.Body)
+	}
+
+	// Повторное удаление уже нечего удалять
+	rec = s.do(t, http.MethodDelete, path, "")
+	expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+
+	rec = s.do(t, http.MethodDelete, APIPrefix+"/users/42", "")
+	expectError(t, rec, http.StatusBadRequest, response.CodeInvalidID)
+}
+
+func TestUpdateUser(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+	s.createUser(t, "bob", "bob@example.com")
+	path := APIPrefix + "/users/" + user.ID.String()
+
+	// Email нормализуется так же, как при создании
+	rec := s.do(t, http.MethodPut, path, `{"username":"alice2","email":" Alice2@Example.com "}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("обновление: статус %d, тело %s", rec.Code, rec.Body)
+	}
+	var updated model.User
+	decodeData(t, rec, &updated)
+	if updated.ID != user.ID || updated.Username != "alice2" || updated.Email != "alice2@example.com" {
+		t.Fatalf("обновлённый пользователь %+v", updated)
+	}
+
+	rec = s.do(t, http.MethodPut, APIPrefix+"/users/"+uuid.NewString(), `{"username":"carol","email":"carol@example.com"}`)
+	expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+
+	rec = s.do(t, http.MethodPut, path, `{"username":"alice2","email":"BOB@example.com"}`)
+	expectError(t, rec, http.StatusConflict, response.CodeEmailTaken)
+
+	rec = s.do(t, http.MethodPut, path, `{"username":"alice2","email":"notanemail"}`)
+	expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+}
+
+func TestPatchUser(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+	s.createUser(t, "bob", "bob@example.com")
+	path := APIPrefix + "/users/" + user.ID.String()
+
+	// Только email: имя остаётся прежним, email нормализуется
+	rec := s.do(t, http.MethodPatch, path, `{"email":" Alice2@Example.com "}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch email: статус %d, тело %s", rec.Code, rec.Body)
+	}
+	var patched model.User
+	decodeData(t, rec, &patched)
+	if patched.Username != "alice" || patched.Email != "alice2@example.com" {
+		t.Fatalf("после patch email получен %+v", patched)
+	}
+
+	// Только имя: email остаётся прежним
+	rec = s.do(t, http.MethodPatch, path, `{"username":"alice_renamed"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch username: статус %d, тело %s", rec.Code, rec.Body)
+	}
+	decodeData(t, rec, &patched)
+	if patched.Username != "alice_renamed" || patched.Email != "alice2@example.com" {
+		t.Fatalf("после patch username получен %+v", patched)
+	}
+
+	// Пустой patch и patch без обновляемых полей
+	for _, body := range []string{`{}`, `{"username":null}`} {
+		rec = s.do(t, http.MethodPatch, path, body)
+		expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+	}
+
+	// Переданные поля проверяются по правилам создания
+	for _, body := range []string{`{"email":"notanemail"}`, `{"email":""}`, `{"username":"ab"}`} {
+		rec = s.do(t, http.MethodPatch, path, body)
+		expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+	}
+
+	rec = s.do(t, http.MethodPatch, path, `{"email":"BOB@example.com"}`)
+	expectError(t, rec, http.StatusConflict, response.CodeEmailTaken)
+
+	rec = s.do(t, http.MethodPatch, APIPrefix+"/users/"+uuid.NewString(), `{"email":"carol@example.com"}`)
+	expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+}
+
+// fetchCount — запрашивает /users/count с параметрами query и возвращает число из data.count
+func (s *testServer) fetchCount(t *testing.T, query string) int64 {
+	t.Helper()
+
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users/count"+query, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("count%s: статус %d, тело %s", query, rec.Code, rec.Body)
+	}
+	var body struct {
+		Count int64 `json:"count"`
+	}
+	decodeData(t, rec, &body)
+
+	return body.Count
+}
+
+func TestCountUsers(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	if count := s.fetchCount(t, ""); count != 0 {
+		t.Fatalf("пустое хранилище: count = %d", count)
+	}
+
+	alice := s.createUser(t, "alice", "alice@example.com")
+	s.createUser(t, "alicia", "alicia@example.com")
+	s.createUser(t, "bob", "bob@example.com")
+	if count := s.fetchCount(t, ""); count != 3 {
+		t.Fatalf("после создания count = %d, ожидалось 3", count)
+	}
+
+	// Мягко удалённые не считаются, пока их не попросили явно
+	if rec := s.do(t, http.MethodDelete, APIPrefix+"/users/"+alice.ID.String(), ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("удаление: статус %d", rec.Code)
+	}
+	if count := s.fetchCount(t, ""); count != 2 {
+		t.Fatalf("после удаления count = %d, ожидалось 2", count)
+	}
+	if count := s.fetchCount(t, "?include_deleted=true"); count != 3 {
+		t.Fatalf("с include_deleted count = %d, ожидалось 3", count)
+	}
+
+	// Фильтр ?q= совпадает со списком
+	if count := s.fetchCount(t, "?q=ali"); count != 1 {
+		t.Fatalf("count?q=ali = %d, ожидалось 1", count)
+	}
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users?q=ali", "")
+	if total := rec.Header().Get("X-Total-Count"); total != "1" {
+		t.Fatalf("X-Total-Count списка с q=ali = %q, ожидалось 1", total)
+	}
+
+	rec = s.do(t, http.MethodGet, APIPrefix+"/users/count?q="+strings.Repeat("a", maxSearchQueryLength+1), "")
+	expectError(t, rec, http.StatusBadRequest, response.CodeInvalidQuery)
+}
+
+func TestListUsersPagination(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	for i := range maxListLimit + 5 {
+		user := model.User{Username: "user" + strconv.Itoa(i), Email: "user" + strconv.Itoa(i) + "@example.com"}
+		if err := s.users.Create(context.Background(), &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	total := strconv.Itoa(maxListLimit + 5)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{query: "", want: defaultListLimit},
+		{query: "?limit=10", want: 10},
+		{query: "?limit=10&offset=200", want: 5},
+		// Слишком большой limit урезается до maxListLimit
+		{query: "?limit=1000", want: maxListLimit},
+	}
+	for _, tt := range tests {
+		rec := s.do(t, http.MethodGet, APIPrefix+"/users"+tt.query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: статус %d, тело %s", tt.query, rec.Code, rec.Body)
+		}
+		var users []model.User
+		decodeData(t, rec, &users)
+		if len(users) != tt.want {
+			t.Fatalf("%q: получено %d пользователей, ожидалось %d", tt.query, len(users), tt.want)
+		}
+		if got := rec.Header().Get("X-Total-Count"); got != total {
+			t.Fatalf("%q: X-Total-Count = %q, ожидалось %s", tt.query, got, total)
+		}
+	}
+
+	for _, query := range []string{"?limit=-1", "?offset=-1", "?limit=abc", "?offset=1.5"} {
+		rec := s.do(t, http.MethodGet, APIPrefix+"/users"+query, "")
+		expectError(t, rec, http.StatusBadRequest, response.CodeInvalidQuery)
+	}
+}
+
+func TestUpdateUserAdvancesUpdatedAt(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+	if user.CreatedAt.IsZero() || !user.UpdatedAt.Equal(user.CreatedAt) {
+		t.Fatalf("у нового пользователя created_at=%v, updated_at=%v", user.CreatedAt, user.UpdatedAt)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	rec := s.do(t, http.MethodPut, APIPrefix+"/users/"+user.ID.String(), `{"username":"alice","email":"alice2@example.com"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("обновление: статус %d, тело %s", rec.Code, rec.Body)
+	}
+	var updated model.User
+	decodeData(t, rec, &updated)
+	if !updated.UpdatedAt.After(user.UpdatedAt) {
+		t.Fatalf("updated_at не изменился: было %v, стало %v", user.UpdatedAt, updated.UpdatedAt)
+	}
+	if !updated.CreatedAt.Equal(user.CreatedAt) {
+		t.Fatalf("created_at изменился при обновлении: было %v, стало %v", user.CreatedAt, updated.CreatedAt)
+	}
+}
+
+func TestUserIDIsUUID(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+	if user.ID == uuid.Nil || user.ID.Version() != 4 {
+		t.Fatalf("созданному пользователю выдан идентификатор %s, ожидался случайный UUID", user.ID)
+	}
+
+	// Каждый маршрут с {id} отклоняет идентификатор, не являющийся UUID, до обращения к хранилищу
+	for _, req := range []struct{ method, path, body string }{
+		{method: http.MethodGet, path: "/users/1"},
+		{method: http.MethodPut, path: "/users/1", body: `{"username":"alice","email":"alice@example.com"}`},
+		{method: http.MethodPatch, path: "/users/1", body: `{"username":"alice"}`},
+		{method: http.MethodDelete, path: "/users/1"},
+		{method: http.MethodPost, path: "/users/1/restore"},
+	} {
+		rec := s.do(t, req.method, APIPrefix+req.path, req.body)
+		expectError(t, rec, http.StatusBadRequest, response.CodeInvalidID)
+	}
+}
+
+// listUsers — запрашивает GET /api/v1/users с параметрами query и возвращает пользователей из ответа
+func (s *testServer) listUsers(t *testing.T, query string) []model.User {
+	t.Helper()
+
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users"+query, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("список %q: статус %d, тело %s", query, rec.Code, rec.Body)
+	}
+	var users []model.User
+	decodeData(t, rec, &users)
+
+	return users
+}
+
+// usernames — имена пользователей по порядку
+func usernames(users []model.User) []string {
+	names := make([]string, 0, len(users))
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+
+	return names
+}
+
+func TestSoftDeletedUsers(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	s.createUser(t, "alice", "alice@example.com")
+	deleted := s.createUser(t, "bob", "bob@example.com")
+
+	rec := s.do(t, http.MethodDelete, APIPrefix+"/users/"+deleted.ID.String(), "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("удаление: статус %d, тело %s", rec.Code, rec.Body)
+	}
+
+	// Удалённый пропадает из обычного списка и из GET по ID
+	if names := usernames(s.listUsers(t, "")); !slices.Equal(names, []string{"alice"}) {
+		t.Fatalf("в списке %v, ожидалась только alice", names)
+	}
+	rec = s.do(t, http.MethodGet, APIPrefix+"/users/"+deleted.ID.String(), "")
+	expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+
+	// С флагом include_deleted запись видна вместе со временем удаления
+	users := s.listUsers(t, "?include_deleted=true&sort=username")
+	if names := usernames(users); !slices.Equal(names, []string{"alice", "bob"}) {
+		t.Fatalf("в списке с include_deleted %v, ожидались alice и bob", names)
+	}
+	if users[0].DeletedAt != nil || users[1].DeletedAt == nil {
+		t.Fatalf("deleted_at: alice %v, bob %v", users[0].DeletedAt, users[1].DeletedAt)
+	}
+
+	rec = s.do(t, http.MethodGet, APIPrefix+"/users?include_deleted=maybe", "")
+	expectError(t, rec, http.StatusBadRequest, response.CodeInvalidQuery)
+}
+
+func TestListUsersSearch(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	s.createUser(t, "alice_admin", "alice@example.com")
+	s.createUser(t, "alicexadmin", "alicex@example.com")
+	s.createUser(t, "bob", "bob@example.com")
+
+	if names := usernames(s.listUsers(t, "?q=ALICE&sort=username")); !slices.Equal(names, []string{"alice_admin", "alicexadmin"}) {
+		t.Fatalf("поиск alice нашёл %v", names)
+	}
+
+	// Без совпадений — пустой массив, а не null
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users?q=nobody", "")
+	if !strings.Contains(rec.Body.String(), `"data":[]`) {
+		t.Fatalf("поиск без совпадений вернул %s, ожидался пустой массив", rec.Body)
+	}
+
+	// _ и % ищутся буквально
+	if names := usernames(s.listUsers(t, "?q=alice_")); !slices.Equal(names, []string{"alice_admin"}) {
+		t.Fatalf("поиск alice_ нашёл %v, ожидался только alice_admin", names)
+	}
+	if names := usernames(s.listUsers(t, "?q=%25")); len(names) != 0 {
+		t.Fatalf("поиск %% нашёл %v", names)
+	}
+
+	// Поиск сочетается с пагинацией: X-Total-Count считает только совпадения
+	rec = s.do(t, http.MethodGet, APIPrefix+"/users?q=alice&limit=1", "")
+	var page []model.User
+	decodeData(t, rec, &page)
+	if len(page) != 1 || rec.Header().Get("X-Total-Count") != "2" {
+		t.Fatalf("страница %v, X-Total-Count %q; ожидались 1 и 2", usernames(page), rec.Header().Get("X-Total-Count"))
+	}
+}
+
+func TestListUsersEmailFilter(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	alice := s.createUser(t, "alice", "alice@example.com")
+	s.createUser(t, "alice2", "alice2@example.com")
+
+	// Регистр и пробелы в запросе не важны, совпадение точное, а не по подстроке
+	for _, email := range []string{"alice@example.com", "ALICE@Example.COM", "%20Alice@example.com%20"} {
+		if names := usernames(s.listUsers(t, "?email="+email)); !slices.Equal(names, []string{"alice"}) {
+			t.Fatalf("email=%s нашёл %v, ожидался только alice", email, names)
+		}
+	}
+
+	// Без совпадений — пустой массив, а не 404
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users?email=nobody@example.com", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"data":[]`) {
+		t.Fatalf("статус %d, тело %s; ожидался пустой массив", rec.Code, rec.Body)
+	}
+
+	// Фильтр сочетается с мягким удалением
+	if rec := s.do(t, http.MethodDelete, APIPrefix+"/users/"+alice.ID.String(), ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("удаление: статус %d", rec.Code)
+	}
+	if names := usernames(s.listUsers(t, "?email=Alice@example.com")); len(names) != 0 {
+		t.Fatalf("email удалённого нашёл %v", names)
+	}
+	if names := usernames(s.listUsers(t, "?email=Alice@example.com&include_deleted=true")); !slices.Equal(names, []string{"alice"}) {
+		t.Fatalf("email с include_deleted нашёл %v", names)
+	}
+}
+
+func TestListUsersSort(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	// Порядок создания отличается от алфавитного, чтобы сортировки различались
+	for _, name := range []string{"bob", "carol", "alice"} {
+		s.createUser(t, name, name+"@example.com")
+		time.Sleep(time.Millisecond)
+	}
+
+	for query, want := range map[string][]string{
+		"":                  {"alice", "carol", "bob"},
+		"?sort=-created_at": {"alice", "carol", "bob"},
+		"?sort=created_at":  {"bob", "carol", "alice"},
+		"?sort=username":    {"alice", "bob", "carol"},
+		"?sort=-username":   {"carol", "bob", "alice"},
+	} {
+		if names := usernames(s.listUsers(t, query)); !slices.Equal(names, want) {
+			t.Errorf("%q: порядок %v, ожидался %v", query, names, want)
+		}
+	}
+
+	// Только поля из списка разрешённых: остальное, включая попытки внедрить SQL, отклоняется
+	for _, value := range []string{"email", "password_hash", "--username", "username%20DESC%2C%20password_hash"} {
+		rec := s.do(t, http.MethodGet, APIPrefix+"/users?sort="+value, "")
+		expectError(t, rec, http.StatusBadRequest, response.CodeInvalidQuery)
+	}
+}
+
+func TestCreateUserConflictField(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	s.createUser(t, "alice", "alice@example.com")
+
+	for _, tt := range []struct {
+		body  string
+		code  string
+		field string
+	}{
+		{body: `{"username":"alice","email":"other@example.com","password":"secret-password"}`,
+			code: response.CodeUsernameTaken, field: "username"},
+		{body: `{"username":"other","email":"alice@example.com","password":"secret-password"}`,
+			code: response.CodeEmailTaken, field: "email"},
+	} {
+		rec := s.do(t, http.MethodPost, APIPrefix+"/users", tt.body)
+		apiErr := expectError(t, rec, http.StatusConflict, tt.code)
+
+		// В details — поле, на котором произошёл конфликт
+		var details conflictDetails
+		if err := json.Unmarshal(apiErr.Details, &details); err != nil {
+			t.Fatal(err)
+		}
+		if details.Field != tt.field {
+			t.Fatalf("конфликт по полю %q, ожидалось %q", details.Field, tt.field)
+		}
+	}
+}
+
+func TestDeleteUsersByFilter(t *testing.T) {
+	opts := testOptions()
+	opts.AdminToken = testAdminToken
+	s := newTestServer(t, opts)
+	s.createUser(t, "test_alice", "test_alice@example.com")
+	s.createUser(t, "test_bob", "test_bob@example.com")
+	s.createUser(t, "carol", "carol@example.com")
+	s.createUser(t, "dave", "dave@example.com")
+
+	// Без фильтра запрос отклоняется и ничего не удаляет
+	rec := s.do(t, http.MethodDelete, APIPrefix+"/users", "", AdminTokenHeader, testAdminToken)
+	expectError(t, rec, http.StatusBadRequest, response.CodeInvalidQuery)
+	if count := s.countUsers(t); count != 4 {
+		t.Fatalf("после запроса без фильтра пользователей %d, ожидалось 4", count)
+	}
+
+	// Удаляются только подходящие под фильтр
+	rec = s.do(t, http.MethodDelete, APIPrefix+"/users?q=test_", "", AdminTokenHeader, testAdminToken)
+	var result map[string]int
+	decodeData(t, rec, &result)
+	if rec.Code != http.StatusOK || result["deleted"] != 2 {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	if got := usernames(s.listUsers(t, "?sort=username")); !slices.Equal(got, []string{"carol", "dave"}) {
+		t.Fatalf("после удаления по q остались %v", got)
+	}
+
+	rec = s.do(t, http.MethodDelete, APIPrefix+"/users?email=carol@example.com", "", AdminTokenHeader, testAdminToken)
+	decodeData(t, rec, &result)
+	if rec.Code != http.StatusOK || result["deleted"] != 1 {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	if got := usernames(s.listUsers(t, "")); !slices.Equal(got, []string{"dave"}) {
+		t.Fatalf("после удаления по email остались %v", got)
+	}
+}
+
+func TestDeleteUsersRequiresAdmin(t *testing.T) {
+	opts := testOptions()
+	opts.AdminToken = testAdminToken
+	s := newTestServer(t, opts)
+	s.createUser(t, "alice", "alice@example.com")
+
+	rec := s.do(t, http.MethodDelete, APIPrefix+"/users?q=alice", "")
+	expectError(t, rec, http.StatusUnauthorized, response.CodeUnauthorized)
+	rec = s.do(t, http.MethodDelete, APIPrefix+"/users?q=alice", "", AdminTokenHeader, "wrong-token")
+	expectError(t, rec, http.StatusForbidden, response.CodeForbidden)
+
+	// Без настроенного ADMIN_TOKEN маршрут закрыт для всех
+	closed := newTestServer(t, testOptions())
+	closed.createUser(t, "alice", "alice@example.com")
+	rec = closed.do(t, http.MethodDelete, APIPrefix+"/users?q=alice", "", AdminTokenHeader, testAdminToken)
+	expectError(t, rec, http.StatusForbidden, response.CodeForbidden)
+
+	if s.countUsers(t) != 1 || closed.countUsers(t) != 1 {
+		t.Fatal("пользователи удалены без прав администратора")
+	}
+}
+
+func TestGetUserByEmail(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	alice := s.createUser(t, "alice", "alice@example.com")
+	tagged := s.createUser(t, "tagged", "alice+news@example.com")
+	deleted := s.createUser(t, "deleted", "deleted@example.com")
+	if rec := s.do(t, http.MethodDelete, APIPrefix+"/users/"+deleted.ID.String(), ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("удаление: статус %d", rec.Code)
+	}
+
+	hits := []struct {
+		name  string
+		email string
+		want  uuid.UUID
+	}{
+		{name: "точное совпадение", email: "alice@example.com", want: alice.ID},
+		{name: "без учёта регистра", email: "Alice@Example.COM", want: alice.ID},
+		{name: "экранированный плюс", email: "alice%2Bnews@example.com", want: tagged.ID},
+		{name: "плюс как есть", email: "alice+news@example.com", want: tagged.ID},
+		{name: "экранированная @", email: "alice%40example.com", want: alice.ID},
+	}
+	for _, tt := range hits {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(t, http.MethodGet, APIPrefix+"/users/by-email/"+tt.email, "")
+			var user model.User
+			decodeData(t, rec, &user)
+			if rec.Code != http.StatusOK || user.ID != tt.want {
+				t.Fatalf("статус %d, тело %s, ожидался %s", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+
+	// Неизвестный и мягко удалённый email не находятся
+	for _, email := range []string{"nobody@example.com", deleted.Email} {
+		rec := s.do(t, http.MethodGet, APIPrefix+"/users/by-email/"+email, "")
+		expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+	}
+
+	rec := s.do(t, http.MethodGet, APIPrefix+"/users/by-email/not-an-email", "")
+	expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+}
+
+func TestRestoreUser(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+	restorePath := APIPrefix + "/users/" + user.ID.String() + "/restore"
+
+	// Активного пользователя восстанавливать нечего
+	rec := s.do(t, http.MethodPost, restorePath, "")
+	expectError(t, rec, http.StatusConflict, response.CodeNotDeleted)
+
+	if rec := s.do(t, http.MethodDelete, APIPrefix+"/users/"+user.ID.String(), ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("удаление: статус %d", rec.Code)
+	}
+	rec = s.do(t, http.MethodPost, restorePath, "")
+	var restored model.User
+	decodeData(t, rec, &restored)
+	if rec.Code != http.StatusOK || restored.ID != user.ID || restored.DeletedAt != nil {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(t, http.MethodGet, APIPrefix+"/users/"+user.ID.String(), ""); rec.Code != http.StatusOK {
+		t.Fatalf("восстановленный пользователь не читается: статус %d", rec.Code)
+	}
+
+	rec = s.do(t, http.MethodPost, APIPrefix+"/users/"+uuid.NewString()+"/restore", "")
+	expectError(t, rec, http.StatusNotFound, response.CodeNotFound)
+	rec = s.do(t, http.MethodPost, APIPrefix+"/users/not-a-uuid/restore", "")
+	expectError(t, rec, http.StatusBadRequest, response.CodeInvalidID)
+}
+
+func TestRestoreUserEmailTaken(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+	if rec := s.do(t, http.MethodDelete, APIPrefix+"/users/"+user.ID.String(), ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("удаление: статус %d", rec.Code)
+	}
+
+	// Пока пользователь был удалён, его email занял другой
+	s.createUser(t, "alice2", "alice@example.com")
+
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users/"+user.ID.String()+"/restore", "")
+	expectError(t, rec, http.StatusConflict, response.CodeEmailTaken)
+	if rec := s.do(t, http.MethodGet, APIPrefix+"/users/"+user.ID.String(), ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("после конфликта пользователь восстановлен: статус %d", rec.Code)
+	}
+}
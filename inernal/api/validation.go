@@ -0,0 +1,179 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+const (
+	// minUsernameLength и maxUsernameLength — допустимая длина имени пользователя в символах
+	minUsernameLength = 3
+	maxUsernameLength = 32
+)
+
+// usernamePattern — допустимые символы имени пользователя; то же правило проверяет CHECK в базе
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// FieldError — ошибка проверки конкретного поля пользователя. Пустое Field — ошибка запроса в целом
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError — все ошибки проверки данных пользователя сразу, а не только первая,
+// чтобы клиент мог подсветить каждое некорректное поле формы
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, fieldErr := range e.Fields {
+		messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+	}
+
+	return "некорректные данные: " + strings.Join(messages, "; ")
+}
+
+// add — добавляет ошибку поля; nil пропускается, чтобы результаты проверок можно было добавлять без условий
+func (e *ValidationError) add(fieldErr *FieldError) {
+	if fieldErr != nil {
+		e.Fields = append(e.Fields, *fieldErr)
+	}
+}
+
+// orNil — возвращает nil, если ошибок не набралось
+func (e *ValidationError) orNil() *ValidationError {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// validateUser — проверяет обязательные поля пользователя и нормализует email.
+// Используется и при создании, и при обновлении, чтобы правила не расходились
+func validateUser(user *model.User) *ValidationError {
+	validationErr := &ValidationError{}
+
+	if user.Username == "" {
+		validationErr.add(&FieldError{Field: "username", Message: "Поле username обязательно"})
+	} else {
+		validationErr.add(validateUsername(user.Username))
+	}
+
+	if user.Email == "" {
+		validationErr.add(&FieldError{Field: "email", Message: "Поле email обязательно"})
+	} else if email, err := normalizeEmail(user.Email); err != nil {
+		// Проверяем формат email и приводим его к каноничному виду
+		validationErr.add(&FieldError{Field: "email", Message: "Некорректный формат email"})
+	} else {
+		user.Email = email
+	}
+
+	// Пароль проверяем, только если он передан: при обновлении его можно не менять
+	if user.Password != "" {
+		validationErr.add(validatePassword(user.Password))
+	}
+
+	return validationErr.orNil()
+}
+
+// validateUsername — проверяет длину и набор символов имени пользователя
+func validateUsername(username string) *FieldError {
+	if length := utf8.RuneCountInString(username); length < minUsernameLength || length > maxUsernameLength {
+		return &FieldError{
+			Field:   "username",
+			Message: fmt.Sprintf("Имя пользователя должно быть длиной от %d до %d символов", minUsernameLength, maxUsernameLength),
+		}
+	}
+	if !usernamePattern.MatchString(username) {
+		return &FieldError{
+			Field:   "username",
+			Message: "Имя пользователя может содержать только латинские буквы, цифры, символы _ и -",
+		}
+	}
+
+	return nil
+}
+
+// validateNewUser — проверки при создании пользователя: правила validateUser плюс обязательный пароль
+func validateNewUser(user *model.User) *ValidationError {
+	validationErr := validateUser(user)
+	if validationErr == nil {
+		validationErr = &ValidationError{}
+	}
+	if user.Password == "" {
+		validationErr.add(&FieldError{Field: "password", Message: "Поле password обязательно"})
+	}
+
+	return validationErr.orNil()
+}
+
+// validatePatch — проверяет переданные поля частичного обновления по тем же правилам, что и при создании,
+// и нормализует email. Пустой patch считается ошибкой: обновлять в нём нечего
+func validatePatch(patch *repository.UserPatch) *ValidationError {
+	validationErr := &ValidationError{}
+	if patch.Username == nil && patch.Email == nil {
+		validationErr.add(&FieldError{Message: "Нужно передать хотя бы одно из полей username или email"})
+		return validationErr
+	}
+
+	if patch.Username != nil {
+		if *patch.Username == "" {
+			validationErr.add(&FieldError{Field: "username", Message: "Поле username не может быть пустым"})
+		} else {
+			validationErr.add(validateUsername(*patch.Username))
+		}
+	}
+
+	if patch.Email != nil {
+		if *patch.Email == "" {
+			validationErr.add(&FieldError{Field: "email", Message: "Поле email не может быть пустым"})
+		} else if email, err := normalizeEmail(*patch.Email); err != nil {
+			validationErr.add(&FieldError{Field: "email", Message: "Некорректный формат email"})
+		} else {
+			patch.Email = &email
+		}
+	}
+
+	return validationErr.orNil()
+}
+
+// writeValidationError — отвечает 400 со списком всех ошибок полей в details.
+// В message попадает описание единственной ошибки или общее сообщение, если их несколько
+func writeValidationError(w http.ResponseWriter, r *http.Request, validationErr *ValidationError) {
+	requestLogger(r).Warn("Некорректные данные пользователя", "error", validationErr.Error())
+
+	message := "Некорректные данные пользователя"
+	if len(validationErr.Fields) == 1 {
+		message = validationErr.Fields[0].Message
+	}
+	response.ErrorWithDetails(w, http.StatusBadRequest, response.CodeValidationFailed, message, validationErr.Fields)
+}
+
+// normalizeEmail — проверяет формат email и приводит его к нижнему регистру без окружающих пробелов,
+// чтобы Foo@Example.com и foo@example.com считались одним адресом
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	// ParseAddress допускает форму "Имя <адрес>", поэтому дополнительно убеждаемся,
+	// что клиент передал только сам адрес
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", err
+	}
+	if addr.Address != email {
+		return "", fmt.Errorf("email %q содержит лишние символы", email)
+	}
+
+	return strings.ToLower(addr.Address), nil
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email   string
+		want    string
+		wantErr bool
+	}{
+		{email: "alice@example.com", want: "alice@example.com"},
+		{email: "Foo@Example.COM", want: "foo@example.com"},
+		{email: "  bob@example.com\t", want: "bob@example.com"},
+		{email: "notanemail", wantErr: true},
+		{email: "@example.com", wantErr: true},
+		{email: "alice@", wantErr: true},
+		// Форма с именем разбирается ParseAddress, но адресом не является
+		{email: "Alice <alice@example.com>", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeEmail(tt.email)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("normalizeEmail(%q) = %q, ожидалась ошибка", tt.email, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeEmail(%q) = %q, %v; ожидалось %q", tt.email, got, err, tt.want)
+		}
+	}
+}
+
+func TestCreateUserEmailValidation(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	// Некорректный email: 400 с указанием поля
+	body := `{"username":"alice","email":"notanemail","password":"secret-password"}`
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users", body)
+	apiErr := expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+	var fields []FieldError
+	if err := json.Unmarshal(apiErr.Details, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 || fields[0].Field != "email" {
+		t.Fatalf("details = %+v, ожидалась ошибка поля email", fields)
+	}
+	if count := s.countUsers(t); count != 0 {
+		t.Fatalf("некорректный email сохранён: пользователей %d", count)
+	}
+
+	// Email в верхнем регистре и с пробелами сохраняется в каноничном виде
+	user := s.createUser(t, "alice", "  Alice@Example.COM ")
+	if user.Email != "alice@example.com" {
+		t.Fatalf("сохранён email %q, ожидался alice@example.com", user.Email)
+	}
+
+	// Тот же адрес в другом регистре — уже занятый email
+	body = `{"username":"alice2","email":"ALICE@example.com","password":"secret-password"}`
+	rec = s.do(t, http.MethodPost, APIPrefix+"/users", body)
+	expectError(t, rec, http.StatusConflict, response.CodeEmailTaken)
+}
+
+func TestValidateUserCollectsAllErrors(t *testing.T) {
+	user := model.User{Username: "ab", Email: "notanemail", Password: "short"}
+	validationErr := validateUser(&user)
+	if validationErr == nil {
+		t.Fatal("некорректный пользователь прошёл проверку")
+	}
+
+	// Проверка не останавливается на первой ошибке: названы все три поля по порядку
+	var fields []string
+	for _, fieldErr := range validationErr.Fields {
+		fields = append(fields, fieldErr.Field)
+	}
+	if !slices.Equal(fields, []string{"username", "email", "password"}) {
+		t.Fatalf("ошибки полей %v, ожидались username, email и password", fields)
+	}
+	if !strings.Contains(validationErr.Error(), "username: ") || !strings.Contains(validationErr.Error(), "email: ") {
+		t.Fatalf("Error() = %q", validationErr.Error())
+	}
+
+	// Корректный пользователь — nil, а не пустой ValidationError
+	user = model.User{Username: "alice", Email: "alice@example.com"}
+	if validationErr := validateUser(&user); validationErr != nil {
+		t.Fatalf("корректный пользователь отклонён: %v", validationErr)
+	}
+}
+
+func TestCreateUserMultipleFieldErrors(t *testing.T) {
+	s := newTestServer(t, testOptions())
+
+	body := `{"username":"ab","email":"notanemail","password":"secret-password"}`
+	rec := s.do(t, http.MethodPost, APIPrefix+"/users", body)
+	apiErr := expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+
+	// Обе ошибки приходят в одном ответе, у каждой своё поле и сообщение
+	var fields []FieldError
+	if err := json.Unmarshal(apiErr.Details, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 2 || fields[0].Field != "username" || fields[1].Field != "email" ||
+		fields[0].Message == "" || fields[1].Message == "" {
+		t.Fatalf("details = %+v, ожидались ошибки username и email", fields)
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		valid    bool
+	}{
+		{username: "abc", valid: true},
+		{username: strings.Repeat("a", maxUsernameLength), valid: true},
+		{username: "Alice_Smith-2", valid: true},
+		{username: "ab", valid: false},
+		{username: strings.Repeat("a", maxUsernameLength+1), valid: false},
+		{username: "alice smith", valid: false},
+		{username: "alice@home", valid: false},
+		// Длина считается в символах, но кириллица не входит в разрешённый набор
+		{username: "алиса", valid: false},
+	}
+	for _, tt := range tests {
+		if fieldErr := validateUsername(tt.username); (fieldErr == nil) != tt.valid {
+			t.Errorf("validateUsername(%q) = %v, ожидалось valid=%v", tt.username, fieldErr, tt.valid)
+		}
+	}
+}
+
+func TestCreateUserUsernameValidation(t *testing.T) {
+	s := newTestServer(t, testOptions())
+	user := s.createUser(t, "alice", "alice@example.com")
+
+	for name, tt := range map[string]struct {
+		username string
+		rule     string
+	}{
+		"слишком короткое": {username: "ab", rule: "длиной от"},
+		"слишком длинное":  {username: strings.Repeat("a", maxUsernameLength+1), rule: "длиной от"},
+		"лишние символы":   {username: "bob!", rule: "только латинские буквы"},
+	} {
+		// Правила одинаковы для создания и обновления
+		for _, req := range []struct{ method, path, body string }{
+			{method: http.MethodPost, path: "/users", body: `{"username":"` + tt.username + `","email":"bob@example.com","password":"secret-password"}`},
+			{method: http.MethodPut, path: "/users/" + user.ID.String(), body: `{"username":"` + tt.username + `","email":"alice@example.com"}`},
+		} {
+			rec := s.do(t, req.method, APIPrefix+req.path, req.body)
+			apiErr := expectError(t, rec, http.StatusBadRequest, response.CodeValidationFailed)
+			// Сообщение называет нарушенное правило
+			if !strings.Contains(apiErr.Message, tt.rule) {
+				t.Fatalf("%s, %s: сообщение %q не называет правило %q", name, req.method, apiErr.Message, tt.rule)
+			}
+		}
+	}
+}
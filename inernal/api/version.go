@@ -0,0 +1,14 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/buildinfo"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// versionHandler — сообщает версию, коммит и время сборки запущенного бинарника, а также версию Go.
+// Помогает убедиться, какая сборка на самом деле развёрнута
+func (h *Handler) versionHandler(w http.ResponseWriter, r *http.Request) {
+	response.Data(w, http.StatusOK, buildinfo.Get(), nil)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/buildinfo"
+)
+
+// setBuildInfo — подставляет значения, как это делает -ldflags -X, и возвращает прежние по завершении теста
+func setBuildInfo(t *testing.T, version, commit, buildTime string) {
+	t.Helper()
+
+	previous := [...]string{buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime}
+	buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime = version, commit, buildTime
+	t.Cleanup(func() {
+		buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime = previous[0], previous[1], previous[2]
+	})
+}
+
+// fetchVersion — запрашивает /version и разбирает data
+func (s *testServer) fetchVersion(t *testing.T) buildinfo.Info {
+	t.Helper()
+
+	rec := s.do(t, http.MethodGet, "/version", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, тело %s", rec.Code, rec.Body)
+	}
+	var info buildinfo.Info
+	decodeData(t, rec, &info)
+
+	return info
+}
+
+func TestVersionInjected(t *testing.T) {
+	setBuildInfo(t, "v1.2.3", "0123abcd", "2026-01-02T03:04:05Z")
+	s := newTestServer(t, testOptions())
+
+	want := buildinfo.Info{Version: "v1.2.3", Commit: "0123abcd", BuildTime: "2026-01-02T03:04:05Z", GoVersion: runtime.Version()}
+	if info := s.fetchVersion(t); info != want {
+		t.Fatalf("/version = %+v, ожидалось %+v", info, want)
+	}
+}
+
+func TestVersionDev(t *testing.T) {
+	// Пустое значение после -ldflags -X тоже считается локальной сборкой
+	setBuildInfo(t, "dev", "", "")
+	s := newTestServer(t, testOptions())
+
+	info := s.fetchVersion(t)
+	if info.Version != "dev" || info.Commit != "dev" || info.BuildTime != "dev" || info.GoVersion != runtime.Version() {
+		t.Fatalf("/version локальной сборки = %+v", info)
+	}
+}
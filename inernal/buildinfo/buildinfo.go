@@ -0,0 +1,48 @@
+// Package buildinfo хранит сведения о сборке, которые подставляются при компиляции:
+//
+//	go build -ldflags "-X github.com/olezhek28/docker-compose-tutorial/inernal/buildinfo.Version=v1.2.3 \
+//		-X github.com/olezhek28/docker-compose-tutorial/inernal/buildinfo.Commit=$(git rev-parse HEAD) \
+//		-X github.com/olezhek28/docker-compose-tutorial/inernal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+package buildinfo
+
+import "runtime"
+
+// unset — значение для локальных сборок без -ldflags
+const unset = "dev"
+
+// Переменные, а не константы: -ldflags -X умеет менять только строковые переменные пакета
+var (
+	// Version — версия релиза
+	Version = unset
+	// Commit — хеш git-коммита, из которого собран бинарник
+	Commit = unset
+	// BuildTime — время сборки в UTC
+	BuildTime = unset
+)
+
+// Info — сведения о запущенной сборке
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get — возвращает сведения о сборке; пустые после -ldflags значения тоже считаются dev
+func Get() Info {
+	return Info{
+		Version:   orUnset(Version),
+		Commit:    orUnset(Commit),
+		BuildTime: orUnset(BuildTime),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// orUnset — подставляет dev вместо пустого значения
+func orUnset(value string) string {
+	if value == "" {
+		return unset
+	}
+
+	return value
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// readEnvFile разбирает файл в формате .env: строки KEY=VALUE, пустые строки и комментарии # пропускаются,
+// допускаются префикс export и значения в одинарных или двойных кавычках.
+// Отсутствующий файл не ошибка: возвращается nil без значений
+func readEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть %s: %w", path, err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: ожидается строка вида KEY=VALUE", path, lineNumber)
+		}
+
+		values[key] = unquoteEnvValue(strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// unquoteEnvValue — снимает парные кавычки; у значения без кавычек отбрасывает комментарий после " #"
+func unquoteEnvValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+
+	return value
+}
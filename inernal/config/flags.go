@@ -0,0 +1,70 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// flagVar — флаг командной строки и переменная окружения, которую он переопределяет
+type flagVar struct {
+	name  string
+	env   string
+	usage string
+	// boolean — флаг без значения, например -skip-migrations
+	boolean bool
+}
+
+// flagVars — настройки, которые можно задать флагами. Флаг важнее переменной окружения,
+// а она — значения по умолчанию
+var flagVars = []flagVar{
+	{name: "addr", env: "HTTP_ADDR", usage: "адрес HTTP-сервера, например :8080"},
+	{name: "db-uri", env: "DB_URI", usage: "строка подключения к Postgres"},
+	{name: "migrations-dir", env: "MIGRATIONS_DIR", usage: "директория миграций; пусто — встроенные миграции"},
+	{name: "log-level", env: "LOG_LEVEL", usage: "уровень логирования: debug, info, warn, error"},
+	{name: "skip-migrations", env: "SKIP_MIGRATIONS", usage: "не применять миграции при запуске сервера", boolean: true},
+}
+
+// parseFlags разбирает аргументы командной строки и возвращает значения явно заданных флагов
+// по именам переменных окружения. На -h печатает справку и возвращает flag.ErrHelp
+func parseFlags(args []string) (map[string]string, error) {
+	fs := flag.NewFlagSet("docker-compose-tutorial", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	values := make(map[string]func() string, len(flagVars))
+	for _, v := range flagVars {
+		usage := fmt.Sprintf("%s (переопределяет %s)", v.usage, v.env)
+		if v.boolean {
+			value := fs.Bool(v.name, false, usage)
+			values[v.name] = func() string { return strconv.FormatBool(*value) }
+			continue
+		}
+		value := fs.String(v.name, "", usage)
+		values[v.name] = func() string { return *value }
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Использование: %s [флаги]\n\n"+
+			"Остальные настройки задаются только переменными окружения.\n\nФлаги:\n", fs.Name())
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("неожиданные аргументы: %v", fs.Args())
+	}
+
+	// Переопределяют окружение только флаги, переданные явно, даже если их значение пустое
+	overrides := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		for _, v := range flagVars {
+			if v.name == f.Name {
+				overrides[v.env] = values[f.Name]()
+			}
+		}
+	})
+
+	return overrides, nil
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loader читает переменные окружения и накапливает ошибки, не прерываясь на первой
+type loader struct {
+	errs []error
+	// overrides — значения из флагов командной строки по именам переменных; важнее окружения
+	overrides map[string]string
+	// fileValues — значения из файла ENV_FILE; используются, только если переменной нет в окружении
+	fileValues map[string]string
+}
+
+// getenv возвращает значение переменной с учётом флагов командной строки и файла ENV_FILE
+func (l *loader) getenv(key string) string {
+	if value, ok := l.overrides[key]; ok {
+		return value
+	}
+	// Настоящее окружение важнее файла, даже если переменная в нём задана пустой
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+
+	return l.fileValues[key]
+}
+
+// string возвращает значение переменной или значение по умолчанию, если переменная не задана
+func (l *loader) string(key, defaultValue string) string {
+	value := l.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
+// list разбирает список значений через запятую, отбрасывая пустые элементы и пробелы вокруг них
+func (l *loader) list(key, defaultValue string) []string {
+	return splitList(l.string(key, defaultValue))
+}
+
+// splitList разбивает строку по запятым, отбрасывая пустые элементы и пробелы вокруг них
+func splitList(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// fileSuffix — суффикс переменной с путём к файлу секрета, например DB_URI_FILE для DB_URI
+const fileSuffix = "_FILE"
+
+// secret — как string, но значение можно передать файлом: если задана переменная key_FILE,
+// значение читается из этого файла без завершающих пробелов и переводов строки и имеет приоритет над key.
+// Так секреты из смонтированных файлов оркестратора не попадают в окружение процесса
+func (l *loader) secret(key, defaultValue string) string {
+	if value, ok := l.fromFile(key); ok {
+		return value
+	}
+
+	return l.string(key, defaultValue)
+}
+
+// secretList — как list, но значение можно передать файлом key_FILE, как в secret
+func (l *loader) secretList(key string) []string {
+	return splitList(l.secret(key, ""))
+}
+
+// fromFile читает значение key из файла, указанного в key_FILE. Возвращает false, если key_FILE не задана;
+// если файл не читается или пуст, фиксирует ошибку, чтобы сервис не стартовал с пустым секретом
+func (l *loader) fromFile(key string) (string, bool) {
+	// Значение из флага важнее и переменной, и файла
+	if _, ok := l.overrides[key]; ok {
+		return "", false
+	}
+
+	path := l.getenv(key + fileSuffix)
+	if path == "" {
+		return "", false
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		l.errorf("%s: не удалось прочитать файл: %v", key+fileSuffix, err)
+		return "", true
+	}
+
+	value := strings.TrimRight(string(content), " \t\r\n")
+	if value == "" {
+		l.errorf("%s: файл %s пуст", key+fileSuffix, path)
+	}
+
+	return value, true
+}
+
+// duration разбирает положительную длительность в формате time.ParseDuration, например 5s или 1m30s
+func (l *loader) duration(key string, defaultValue time.Duration) time.Duration {
+	value := l.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		l.errorf("%s: некорректная длительность %q", key, value)
+		return defaultValue
+	}
+	if duration <= 0 {
+		l.errorf("%s: длительность должна быть положительной, получено %q", key, value)
+		return defaultValue
+	}
+
+	return duration
+}
+
+// nonNegativeDuration разбирает длительность, допуская 0 — для настроек, где ноль выключает ограничение
+func (l *loader) nonNegativeDuration(key string, defaultValue time.Duration) time.Duration {
+	value := l.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		l.errorf("%s: некорректная длительность %q", key, value)
+		return defaultValue
+	}
+	if duration < 0 {
+		l.errorf("%s: длительность не может быть отрицательной, получено %q", key, value)
+		return defaultValue
+	}
+
+	return duration
+}
+
+// int32 разбирает целое число, помещающееся в int32
+func (l *loader) int32(key string, defaultValue int32) int32 {
+	value := l.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		l.errorf("%s: некорректное целое число %q", key, value)
+		return defaultValue
+	}
+
+	return int32(number)
+}
+
+// int разбирает целое число
+func (l *loader) int(key string, defaultValue int) int {
+	value := l.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		l.errorf("%s: некорректное целое число %q", key, value)
+		return defaultValue
+	}
+
+	return number
+}
+
+// float64 разбирает число с плавающей точкой
+func (l *loader) float64(key string, defaultValue float64) float64 {
+	value := l.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		l.errorf("%s: некорректное число %q", key, value)
+		return defaultValue
+	}
+
+	return number
+}
+
+// bool разбирает логическое значение в формате strconv.ParseBool: true/false, 1/0 и т.п.
+func (l *loader) bool(key string, defaultValue bool) bool {
+	value := l.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		l.errorf("%s: некорректное логическое значение %q", key, value)
+		return defaultValue
+	}
+
+	return flag
+}
+
+// logLevel разбирает уровень логирования slog: debug, info, warn или error
+func (l *loader) logLevel(key string, defaultValue slog.Level) slog.Level {
+	value := l.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		l.errorf("%s: неизвестный уровень логирования %q", key, value)
+		return defaultValue
+	}
+
+	return level
+}
+
+// errorf фиксирует ошибку проверки
+func (l *loader) errorf(format string, args ...any) {
+	l.errs = append(l.errs, fmt.Errorf(format, args...))
+}
+
+// err возвращает все накопленные ошибки одной ошибкой или nil, если ошибок не было
+func (l *loader) err() error {
+	if len(l.errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("некорректная конфигурация: %w", errors.Join(l.errs...))
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedPath — значение метки path для запросов, не совпавших ни с одним маршрутом.
+// Подставлять сырой URL нельзя: иначе число временных рядов будет расти бесконтрольно
+const unmatchedPath = "unmatched"
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Количество обработанных HTTP-запросов по методу, маршруту и коду ответа.",
+	}, []string{"method", "path", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Длительность обработки HTTP-запросов в секундах.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path"})
+)
+
+// Handler возвращает обработчик, отдающий метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware измеряет длительность и считает запросы для всех маршрутов, которые оборачивает
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		// ServeMux записывает в r.Pattern шаблон совпавшего маршрута, например "GET /users/{id}"
+		path := r.Pattern
+		if path == "" {
+			path = unmatchedPath
+		}
+
+		requestsTotal.WithLabelValues(r.Method, path, strconv.Itoa(recorder.status)).Inc()
+		requestDuration.WithLabelValues(r.Method, path).Observe(time.Since(start).Seconds())
+	})
+}
+
+// RegisterPoolStats экспортирует состояние пула соединений, считывая db.Stat() при каждом сборе метрик
+func RegisterPoolStats(db *pgxpool.Pool) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "db_pool_acquired_conns",
+		Help: "Количество соединений, занятых в данный момент.",
+	}, func() float64 {
+		return float64(db.Stat().AcquiredConns())
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "db_pool_idle_conns",
+		Help: "Количество свободных соединений в пуле.",
+	}, func() float64 {
+		return float64(db.Stat().IdleConns())
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "db_pool_total_conns",
+		Help: "Общее количество открытых соединений в пуле.",
+	}, func() float64 {
+		return float64(db.Stat().TotalConns())
+	})
+}
+
+// circuitBreakerState — состояние выключателя базы: 0 — замкнут, 1 — полуоткрыт, 2 — разомкнут
+var circuitBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "db_circuit_breaker_state",
+	Help: "Состояние автоматического выключателя базы данных: 0 — замкнут, 1 — полуоткрыт, 2 — разомкнут.",
+})
+
+// SetCircuitBreakerState обновляет метрику состояния выключателя базы
+func SetCircuitBreakerState(state float64) {
+	circuitBreakerState.Set(state)
+}
+
+var (
+	migrationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "db_migration_duration_seconds",
+		Help: "Длительность применения миграций схемы базы данных в секундах.",
+		// Миграции бывают и мгновенными, и многоминутными, поэтому сетка шире стандартной
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	}, []string{"version"})
+
+	migrationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_migrations_total",
+		Help: "Количество применений миграций схемы базы данных по версии и результату: success или failure.",
+	}, []string{"version", "status"})
+)
+
+// MigrationRecorder — получатель метрик мигратора, экспортирующий их в Prometheus
+type MigrationRecorder struct{}
+
+// ObserveMigration учитывает длительность и результат применения миграции version
+func (MigrationRecorder) ObserveMigration(version int64, duration time.Duration, err error) {
+	label := strconv.FormatInt(version, 10)
+	status := "success"
+	if err != nil {
+		status = "failure"
+	}
+
+	migrationDuration.WithLabelValues(label).Observe(duration.Seconds())
+	migrationsTotal.WithLabelValues(label, status).Inc()
+}
+
+// statusRecorder запоминает код ответа, так как http.ResponseWriter его не раскрывает
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/tracing"
+)
+
+// AccessLog пишет в лог каждый обработанный запрос: метод, путь, код ответа, размер тела и длительность.
+// Успешные ответы логируются на уровне info, 4xx — warn, 5xx — error
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		level := slog.LevelInfo
+		switch {
+		case recorder.status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case recorder.status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", r.Pattern,
+			"status", recorder.status,
+			"bytes", recorder.bytes,
+			"duration", time.Since(start).String(),
+			"request_id", RequestIDFromContext(r.Context()),
+		}
+		slog.Log(r.Context(), level, "Запрос обработан", append(attrs, tracing.LogAttrs(r.Context())...)...)
+	})
+}
+
+// responseRecorder запоминает код ответа и число записанных байт тела
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
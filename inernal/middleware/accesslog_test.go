@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/tracing"
+)
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		level  string
+	}{
+		{name: "успех", status: http.StatusCreated, level: "INFO"},
+		{name: "ошибка клиента", status: http.StatusNotFound, level: "WARN"},
+		{name: "ошибка сервера", status: http.StatusServiceUnavailable, level: "ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /users/{id}", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("hello"))
+			})
+			// Тот же порядок, что в main: request ID снаружи, чтобы попасть в запись журнала
+			handler := RequestID(AccessLog(mux))
+
+			req := httptest.NewRequest(http.MethodPost, "/users/42", nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			entries := logs.entries(t)
+			if len(entries) != 1 {
+				t.Fatalf("записей в логе %d, ожидалась одна", len(entries))
+			}
+			entry := entries[0]
+			// Числа в JSON разбираются как float64
+			if entry["status"] != float64(tt.status) || entry["level"] != tt.level {
+				t.Fatalf("в логе status=%v, level=%v; ожидались %d и %s", entry["status"], entry["level"], tt.status, tt.level)
+			}
+			for key, want := range map[string]any{
+				"method":     http.MethodPost,
+				"path":       "/users/42",
+				"route":      "POST /users/{id}",
+				"bytes":      float64(len("hello")),
+				"request_id": "req-1",
+			} {
+				if entry[key] != want {
+					t.Fatalf("%s = %v, ожидалось %v", key, entry[key], want)
+				}
+			}
+			if entry["duration"] == "" || entry["duration"] == nil {
+				t.Fatal("в записи нет длительности")
+			}
+		})
+	}
+}
+
+func TestAccessLogImplicitStatus(t *testing.T) {
+	logs := captureLogs(t)
+
+	// Обработчик, не вызвавший WriteHeader, отвечает 200, и в журнале тоже 200
+	AccessLog(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if entries := logs.entries(t); len(entries) != 1 || entries[0]["status"] != float64(http.StatusOK) {
+		t.Fatalf("записи лога %v, ожидался status 200", entries)
+	}
+}
+
+func TestAccessLogUpstreamTrace(t *testing.T) {
+	logs := captureLogs(t)
+	// Setup без адреса коллектора только включает формат traceparent, как в сервисе без экспорта
+	previous := otel.GetTextMapPropagator()
+	if _, err := tracing.Setup(context.Background(), "", "test"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	// Тот же порядок, что в main: трассировка снаружи всех middleware
+	handler := tracing.Middleware(RequestID(AccessLog(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))))
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(RequestIDHeader, "gateway-7")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Запись журнала ссылается на трейс и идентификатор запроса вызывающего сервиса
+	entries := logs.entries(t)
+	if len(entries) != 1 {
+		t.Fatalf("записей в логе %d, ожидалась одна", len(entries))
+	}
+	if entries[0]["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || entries[0]["request_id"] != "gateway-7" {
+		t.Fatalf("в логе trace_id=%v, request_id=%v", entries[0]["trace_id"], entries[0]["request_id"])
+	}
+}
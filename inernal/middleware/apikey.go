@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// APIKeyHeader — заголовок, в котором клиент передаёт API-ключ
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyIDLength — сколько символов хеша ключа пишется в лог: достаточно, чтобы отличить ключи, и не раскрывает их
+const apiKeyIDLength = 8
+
+// APIKeyOptions — настройки аутентификации по статическим API-ключам
+type APIKeyOptions struct {
+	// Keys — допустимые ключи; несколько сразу, чтобы новый ключ можно было выдать до отзыва старого
+	Keys []string
+	// PublicPaths — пути, доступные без ключа, например пробы оркестратора
+	PublicPaths []string
+}
+
+// APIKeyAuth требует в заголовке X-API-Key один из допустимых ключей и отвечает 401 без него или с неизвестным ключом.
+// Для аудита каждый запрос логируется с префиксом хеша использованного ключа, а не с самим ключом
+func APIKeyAuth(opts APIKeyOptions) func(http.Handler) http.Handler {
+	// Сравниваем хеши фиксированной длины: ConstantTimeCompare раскрывает различие длин
+	hashes := make([][sha256.Size]byte, len(opts.Keys))
+	for i, key := range opts.Keys {
+		hashes[i] = sha256.Sum256([]byte(key))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(opts.PublicPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				apiKeyUnauthorized(w, "Требуется API-ключ в заголовке "+APIKeyHeader)
+				return
+			}
+
+			got := sha256.Sum256([]byte(key))
+			keyID := hex.EncodeToString(got[:])[:apiKeyIDLength]
+
+			// Перебираем все ключи без досрочного выхода, чтобы время ответа не зависело от позиции ключа
+			match := 0
+			for i := range hashes {
+				match |= subtle.ConstantTimeCompare(got[:], hashes[i][:])
+			}
+			if match != 1 {
+				slog.Warn("Неизвестный API-ключ", "path", r.URL.Path, "api_key_id", keyID,
+					"request_id", RequestIDFromContext(r.Context()))
+				apiKeyUnauthorized(w, "Неверный API-ключ")
+				return
+			}
+
+			slog.Info("Запрос с API-ключом", "method", r.Method, "path", r.URL.Path, "api_key_id", keyID,
+				"request_id", RequestIDFromContext(r.Context()))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiKeyUnauthorized отвечает 401 и называет ожидаемую схему авторизации
+func apiKeyUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `ApiKey header="`+APIKeyHeader+`"`)
+	response.Error(w, http.StatusUnauthorized, response.CodeUnauthorized, message)
+}
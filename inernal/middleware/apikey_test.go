@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	currentAPIKey  = "current-key-0123456789"
+	previousAPIKey = "previous-key-0123456789"
+)
+
+// apiKeyRequest — прогоняет запрос с заголовком X-API-Key через APIKeyAuth с двумя действующими ключами
+func apiKeyRequest(t *testing.T, path, key string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	handler := APIKeyAuth(APIKeyOptions{Keys: []string{currentAPIKey, previousAPIKey}, PublicPaths: []string{"/healthz"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestAPIKeyAuthValidKey(t *testing.T) {
+	logs := captureLogs(t)
+
+	// При ротации принимаются и новый, и ещё не отозванный старый ключ
+	for _, key := range []string{currentAPIKey, previousAPIKey} {
+		if rec := apiKeyRequest(t, "/api/v1/users", key); rec.Code != http.StatusOK {
+			t.Fatalf("ключ %s: статус %d, ожидался 200", key, rec.Code)
+		}
+	}
+
+	// В лог аудита попадает префикс хеша, разный для разных ключей, но не сам ключ
+	entries := logs.entries(t)
+	if len(entries) != 2 {
+		t.Fatalf("записей в логе %d, ожидалось 2", len(entries))
+	}
+	first, _ := entries[0]["api_key_id"].(string)
+	second, _ := entries[1]["api_key_id"].(string)
+	if len(first) != apiKeyIDLength || len(second) != apiKeyIDLength || first == second {
+		t.Fatalf("api_key_id %q и %q: ожидались два разных префикса длины %d", first, second, apiKeyIDLength)
+	}
+	for _, entry := range entries {
+		for _, value := range entry {
+			if s, ok := value.(string); ok && (strings.Contains(s, currentAPIKey) || strings.Contains(s, previousAPIKey)) {
+				t.Fatalf("ключ попал в лог: %v", entry)
+			}
+		}
+	}
+}
+
+func TestAPIKeyAuthRejectsKey(t *testing.T) {
+	captureLogs(t)
+
+	for name, key := range map[string]string{"нет заголовка": "", "неизвестный ключ": "unknown-key-0123456789"} {
+		t.Run(name, func(t *testing.T) {
+			rec := apiKeyRequest(t, "/api/v1/users", key)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("статус %d, ожидался 401", rec.Code)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, "ApiKey") {
+				t.Fatalf("WWW-Authenticate = %q, ожидалась схема ApiKey", got)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthPublicPath(t *testing.T) {
+	rec := apiKeyRequest(t, "/healthz", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("публичный путь: статус %d, ожидался 200", rec.Code)
+	}
+}
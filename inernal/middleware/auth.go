@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// jwtSigningMethod — единственный принимаемый алгоритм подписи; токены с другим алгоритмом отклоняются
+var jwtSigningMethod = jwt.SigningMethodHS256
+
+// ErrMissingSubject возвращается, когда подпись токена верна, но в нём нет идентификатора пользователя
+var ErrMissingSubject = errors.New("в токене нет идентификатора пользователя")
+
+// AuthOptions — настройки проверки JWT
+type AuthOptions struct {
+	// Secret — общий секрет для подписи HS256
+	Secret []byte
+	// PublicPaths — пути, доступные без токена, например пробы оркестратора
+	PublicPaths []string
+}
+
+type userIDKey struct{}
+
+// JWTAuth проверяет заголовок Authorization: Bearer <token>. Отсутствующий, просроченный или неверно
+// подписанный токен — 401; токен без обязательного claim sub с UUID пользователя — 403.
+// Идентификатор пользователя из токена сохраняется в контексте запроса
+func JWTAuth(opts AuthOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(opts.PublicPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				unauthorized(w, "Требуется токен авторизации")
+				return
+			}
+
+			userID, err := ParseToken(opts.Secret, token)
+			if errors.Is(err, ErrMissingSubject) {
+				slog.Warn("В токене нет обязательного claim", "path", r.URL.Path, "error", err)
+				response.Error(w, http.StatusForbidden, response.CodeForbidden, "В токене нет идентификатора пользователя")
+				return
+			}
+			if err != nil {
+				slog.Warn("Некорректный токен авторизации", "path", r.URL.Path, "error", err)
+				unauthorized(w, "Некорректный или просроченный токен")
+				return
+			}
+
+			req := r.WithContext(WithUserID(r.Context(), userID))
+			next.ServeHTTP(w, req)
+			// ServeMux записывает шаблон маршрута в копию запроса; возвращаем его внешним middleware
+			r.Pattern = req.Pattern
+		})
+	}
+}
+
+// ParseToken проверяет подпись и срок действия токена и возвращает идентификатор пользователя из claim sub
+func ParseToken(secret []byte, token string) (uuid.UUID, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwtSigningMethod.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if claims.Subject == "" {
+		return uuid.Nil, ErrMissingSubject
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, errors.Join(ErrMissingSubject, err)
+	}
+
+	return userID, nil
+}
+
+// NewToken выпускает подписанный токен для пользователя со сроком действия ttl.
+// Нужен для тестов и утилит: сам сервис токены пока не выдаёт
+func NewToken(secret []byte, userID uuid.UUID, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Subject:   userID.String(),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+
+	return jwt.NewWithClaims(jwtSigningMethod, claims).SignedString(secret)
+}
+
+// WithUserID возвращает копию контекста с идентификатором аутентифицированного пользователя
+func WithUserID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+// UserIDFromContext достаёт идентификатор аутентифицированного пользователя из контекста
+func UserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(userIDKey{}).(uuid.UUID)
+	return id, ok
+}
+
+// unauthorized отвечает 401 и подсказывает клиенту схему авторизации
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer`)
+	response.Error(w, http.StatusUnauthorized, response.CodeUnauthorized, message)
+}
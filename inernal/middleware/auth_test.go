@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var testSecret = []byte("test-secret-that-is-long-enough-for-hs256")
+
+// authRequest — прогоняет запрос с заголовком Authorization через JWTAuth и возвращает ответ
+// и идентификатор пользователя, который увидел обработчик
+func authRequest(t *testing.T, path, authorization string) (*httptest.ResponseRecorder, uuid.UUID) {
+	t.Helper()
+
+	var seen uuid.UUID
+	handler := JWTAuth(AuthOptions{Secret: testSecret, PublicPaths: []string{"/healthz"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen, _ = UserIDFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec, seen
+}
+
+// signClaims — подписывает произвольные claims общим секретом
+func signClaims(t *testing.T, secret []byte, claims jwt.Claims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwtSigningMethod, claims).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestJWTAuthValidToken(t *testing.T) {
+	userID := uuid.New()
+	token, err := NewToken(testSecret, userID, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, seen := authRequest(t, "/api/v1/users", "Bearer "+token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200, тело %s", rec.Code, rec.Body)
+	}
+	if seen != userID {
+		t.Fatalf("в контексте пользователь %s, ожидался %s", seen, userID)
+	}
+}
+
+func TestJWTAuthRejectsToken(t *testing.T) {
+	expired, err := NewToken(testSecret, uuid.New(), -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := NewToken([]byte("another-secret-that-is-long-enough-too"), uuid.New(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noExpiry := signClaims(t, testSecret, jwt.RegisteredClaims{Subject: uuid.NewString()})
+
+	tests := []struct {
+		name          string
+		authorization string
+	}{
+		{name: "нет заголовка", authorization: ""},
+		{name: "не Bearer", authorization: "Basic dXNlcjpwYXNz"},
+		{name: "пустой токен", authorization: "Bearer "},
+		{name: "просроченный", authorization: "Bearer " + expired},
+		{name: "чужая подпись", authorization: "Bearer " + foreign},
+		{name: "без срока действия", authorization: "Bearer " + noExpiry},
+		{name: "мусор", authorization: "Bearer not-a-jwt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, _ := authRequest(t, "/api/v1/users", tt.authorization)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("статус %d, ожидался 401", rec.Code)
+			}
+			if rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Fatalf("WWW-Authenticate = %q, ожидался Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestJWTAuthRequiresSubject(t *testing.T) {
+	expiresAt := jwt.NewNumericDate(time.Now().Add(time.Minute))
+	for name, subject := range map[string]string{"без sub": "", "sub не UUID": "alice"} {
+		t.Run(name, func(t *testing.T) {
+			token := signClaims(t, testSecret, jwt.RegisteredClaims{Subject: subject, ExpiresAt: expiresAt})
+
+			rec, _ := authRequest(t, "/api/v1/users", "Bearer "+token)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("статус %d, ожидался 403", rec.Code)
+			}
+		})
+	}
+}
+
+func TestJWTAuthPublicPath(t *testing.T) {
+	rec, _ := authRequest(t, "/healthz", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("публичный путь: статус %d, ожидался 200", rec.Code)
+	}
+}
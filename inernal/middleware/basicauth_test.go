@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// basicAuthRequest — прогоняет запрос через BasicAuth с логином admin и паролем s3cret;
+// пустой user означает запрос без заголовка Authorization
+func basicAuthRequest(t *testing.T, path, user, password string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	handler := BasicAuth(BasicAuthOptions{User: "admin", Password: "s3cret", PublicPaths: []string{"/healthz"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if user != "" {
+		req.SetBasicAuth(user, password)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestBasicAuthValidCredentials(t *testing.T) {
+	rec := basicAuthRequest(t, "/api/v1/users", "admin", "s3cret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200, тело %s", rec.Code, rec.Body)
+	}
+}
+
+func TestBasicAuthRejectsCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		password string
+	}{
+		{name: "нет заголовка"},
+		{name: "неверный пароль", user: "admin", password: "wrong"},
+		{name: "неверный логин", user: "root", password: "s3cret"},
+		{name: "пароль — префикс верного", user: "admin", password: "s3c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := basicAuthRequest(t, "/api/v1/users", tt.user, tt.password)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("статус %d, ожидался 401", rec.Code)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, `Basic realm="`) {
+				t.Fatalf("WWW-Authenticate = %q, ожидалась схема Basic", got)
+			}
+		})
+	}
+}
+
+func TestBasicAuthPublicPath(t *testing.T) {
+	rec := basicAuthRequest(t, "/healthz", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("публичный путь: статус %d, ожидался 200", rec.Code)
+	}
+}
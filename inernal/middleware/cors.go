@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// corsMaxAge — сколько секунд браузер может кешировать результат preflight-запроса
+const corsMaxAge = "600"
+
+// CORSOptions — настройки CORS: разрешённые источники, методы и заголовки
+type CORSOptions struct {
+	// AllowedOrigins — список разрешённых источников; "*" разрешает любой источник
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+}
+
+// CORS обрабатывает preflight-запросы и проставляет заголовки Access-Control-Allow-* для разрешённых источников.
+// Запросы с источником не из списка отклоняются с 403. Пустой список означает, что CORS выключен:
+// заголовки не выставляются, и браузер сам заблокирует межсайтовые запросы
+func CORS(opts CORSOptions) func(http.Handler) http.Handler {
+	allowedMethods := strings.Join(opts.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(opts.AllowedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		if len(opts.AllowedOrigins) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			// Запрос без Origin — не CORS-запрос, пропускаем без изменений
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Ответ зависит от Origin, поэтому промежуточные кеши должны это учитывать
+			w.Header().Add("Vary", "Origin")
+
+			if !isAllowedOrigin(opts.AllowedOrigins, origin) {
+				response.Error(w, http.StatusForbidden, response.CodeForbiddenOrigin, "Источник запроса не разрешён")
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+
+			// Preflight-запрос: браузер спрашивает разрешение до отправки основного запроса
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+				w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+				w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isAllowedOrigin проверяет источник по списку разрешённых
+func isAllowedOrigin(allowed []string, origin string) bool {
+	return slices.Contains(allowed, "*") || slices.Contains(allowed, origin)
+}
@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// gzipMinSize — тела меньше этого размера отдаются без сжатия: выигрыш не окупает заголовки gzip
+const gzipMinSize = 1024
+
+// incompressibleTypes — типы содержимого, которые уже сжаты или передаются потоком и сжимать не нужно
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"text/event-stream",
+}
+
+// Gzip сжимает тело ответа, если клиент прислал Accept-Encoding: gzip. Маленькие тела и уже
+// сжатые типы содержимого отдаются как есть. Решение принимается по первым gzipMinSize байтам,
+// поэтому код ответа и заголовки отправляются клиенту только после него
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Ответ зависит от Accept-Encoding, поэтому промежуточные кеши должны это учитывать
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.finish()
+
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip сообщает, разрешил ли клиент gzip в заголовке Accept-Encoding (gzip;q=0 означает запрет)
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+
+		value, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		q, err := strconv.ParseFloat(value, 64)
+		return err == nil && q > 0
+	}
+
+	return false
+}
+
+// gzipResponseWriter копит начало тела, чтобы решить, сжимать ли ответ, и затем пишет либо
+// через gzip.Writer, либо напрямую в исходный ResponseWriter
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	// Код ответа отправим вместе с решением о сжатии; информационные 1xx передаём сразу
+	if status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if !w.decided {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= gzipMinSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// Flush отправляет накопленные данные клиенту; при сжатии сбрасывает и буфер gzip
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(len(w.buf) >= gzipMinSize); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		if err := w.gz.Flush(); err != nil {
+			return
+		}
+	}
+	if err := http.NewResponseController(w.ResponseWriter).Flush(); err != nil {
+		slog.Debug("ResponseWriter не поддерживает Flush", "error", err)
+	}
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide отправляет заголовки и накопленный буфер; сжатие включается, если large и тип содержимого подходит
+func (w *gzipResponseWriter) decide(large bool) error {
+	w.decided = true
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// Определяем тип по несжатым данным, иначе net/http определил бы его по байтам gzip
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if large && compressible(header) {
+		header.Set("Content-Encoding", "gzip")
+		// Длина несжатого тела больше не соответствует тому, что уйдёт клиенту
+		header.Del("Content-Length")
+		w.ResponseWriter.WriteHeader(w.status)
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+		_, err := w.gz.Write(w.buf)
+		w.buf = nil
+		return err
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// finish дописывает ответ после того, как обработчик завершился
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		// Всё тело поместилось в буфер и оказалось меньше порога — отдаём без сжатия
+		if len(w.buf) == 0 {
+			w.decided = true
+			w.ResponseWriter.WriteHeader(w.status)
+			return
+		}
+		if err := w.decide(false); err != nil {
+			slog.Debug("Ошибка записи ответа", "error", err)
+		}
+		return
+	}
+
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			slog.Debug("Ошибка завершения gzip-потока", "error", err)
+		}
+	}
+}
+
+// compressible сообщает, стоит ли сжимать ответ с такими заголовками
+func compressible(header http.Header) bool {
+	// Обработчик мог уже закодировать тело сам
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	return !slices.ContainsFunc(incompressibleTypes, func(prefix string) bool {
+		return strings.HasPrefix(contentType, prefix)
+	})
+}
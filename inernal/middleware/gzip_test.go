@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveGzip — прогоняет запрос с заголовком Accept-Encoding через Gzip поверх handler
+func serveGzip(handler http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	Gzip(handler).ServeHTTP(rec, req)
+
+	return rec
+}
+
+// bodyHandler — отвечает телом body с типом contentType и кодом status
+func bodyHandler(status int, contentType, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		// Пишем частями, как json.Encoder на длинном списке
+		for _, chunk := range strings.Split(body, "\n") {
+			_, _ = io.WriteString(w, chunk+"\n")
+		}
+	})
+}
+
+func TestGzipRoundTrip(t *testing.T) {
+	body := strings.Repeat(`{"username":"alice","email":"alice@example.com"}`+"\n", 100)
+
+	rec := serveGzip(bodyHandler(http.StatusCreated, "application/json", strings.TrimSuffix(body, "\n")), "gzip, deflate")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("статус %d, ожидался 201", rec.Code)
+	}
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("заголовки %v, ожидались Content-Encoding: gzip и Vary", rec.Header())
+	}
+
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decompressed) != body {
+		t.Fatalf("после распаковки %d байт, ожидалось %d", len(decompressed), len(body))
+	}
+}
+
+func TestGzipSkipped(t *testing.T) {
+	large := strings.Repeat("x", gzipMinSize*2)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+	}{
+		{name: "клиент не поддерживает gzip", contentType: "application/json", body: large},
+		{name: "gzip запрещён q=0", acceptEncoding: "gzip;q=0", contentType: "application/json", body: large},
+		{name: "маленькое тело", acceptEncoding: "gzip", contentType: "application/json", body: `{"data":[]}`},
+		{name: "уже сжатый тип", acceptEncoding: "gzip", contentType: "image/png", body: large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveGzip(bodyHandler(http.StatusOK, tt.contentType, tt.body), tt.acceptEncoding)
+			if rec.Header().Get("Content-Encoding") != "" {
+				t.Fatal("ответ сжат, хотя не должен")
+			}
+			if rec.Body.String() != tt.body+"\n" {
+				t.Fatalf("тело изменено: %d байт вместо %d", rec.Body.Len(), len(tt.body)+1)
+			}
+		})
+	}
+}
+
+func TestGzipFlush(t *testing.T) {
+	// Поток, сбрасываемый до конца обработчика: заголовки уходят сразу, а сжатые данные читаются
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = io.WriteString(w, strings.Repeat("a", gzipMinSize))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+		_, _ = io.WriteString(w, "tail")
+	})
+
+	rec := serveGzip(handler, "gzip")
+	if !rec.Flushed || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Flushed=%v, Content-Encoding=%q", rec.Flushed, rec.Header().Get("Content-Encoding"))
+	}
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decompressed) != strings.Repeat("a", gzipMinSize)+"tail" {
+		t.Fatalf("после распаковки %q", decompressed)
+	}
+}
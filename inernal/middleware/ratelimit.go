@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+const (
+	// rateLimiterIdleTTL — через сколько времени без запросов лимитер клиента удаляется из памяти
+	rateLimiterIdleTTL = 3 * time.Minute
+	// rateLimiterCleanupInterval — как часто проверяем карту лимитеров на устаревшие записи
+	rateLimiterCleanupInterval = time.Minute
+)
+
+// RateLimitOptions — настройки ограничения частоты запросов
+type RateLimitOptions struct {
+	// RPS — допустимое среднее число запросов в секунду от одного клиента
+	RPS float64
+	// Burst — сколько запросов клиент может отправить разом сверх среднего темпа
+	Burst int
+	// TrustProxy — брать адрес клиента из X-Forwarded-For; включать только за доверенным прокси
+	TrustProxy bool
+}
+
+// RateLimiter ограничивает частоту запросов по алгоритму token bucket отдельно для каждого IP-адреса
+type RateLimiter struct {
+	opts RateLimitOptions
+
+	mu      sync.Mutex
+	clients map[string]*rateLimitClient
+}
+
+// rateLimitClient — лимитер клиента и время его последнего запроса для очистки
+type rateLimitClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter создаёт лимитер и запускает фоновую очистку неактивных клиентов,
+// которая работает до отмены ctx
+func NewRateLimiter(ctx context.Context, opts RateLimitOptions) *RateLimiter {
+	rl := &RateLimiter{
+		opts:    opts,
+		clients: make(map[string]*rateLimitClient),
+	}
+
+	go rl.cleanup(ctx)
+
+	return rl
+}
+
+// Middleware отклоняет запросы сверх лимита с кодом 429 и заголовком Retry-After
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reservation := rl.limiter(rl.clientIP(r)).Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			// Токен не будет списан: запрос отклоняется, а не ставится в очередь
+			reservation.Cancel()
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			response.Error(w, http.StatusTooManyRequests, response.CodeRateLimited, "Слишком много запросов, повторите позже")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limiter возвращает лимитер клиента, создавая его при первом обращении
+func (rl *RateLimiter) limiter(ip string) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	client, ok := rl.clients[ip]
+	if !ok {
+		client = &rateLimitClient{limiter: rate.NewLimiter(rate.Limit(rl.opts.RPS), rl.opts.Burst)}
+		rl.clients[ip] = client
+	}
+	client.lastSeen = time.Now()
+
+	return client.limiter
+}
+
+// cleanup периодически удаляет лимитеры клиентов, давно не присылавших запросов,
+// чтобы карта не росла бесконечно
+func (rl *RateLimiter) cleanup(ctx context.Context) {
+	ticker := time.NewTicker(rateLimiterCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rl.mu.Lock()
+			for ip, client := range rl.clients {
+				if time.Since(client.lastSeen) > rateLimiterIdleTTL {
+					delete(rl.clients, ip)
+				}
+			}
+			rl.mu.Unlock()
+		}
+	}
+}
+
+// clientIP определяет адрес клиента. При доверенном прокси берётся последний адрес из X-Forwarded-For —
+// его добавил сам прокси, тогда как начало списка клиент может подделать
+func (rl *RateLimiter) clientIP(r *http.Request) string {
+	if rl.opts.TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			parts := strings.Split(forwarded, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/tracing"
+)
+
+// Recover перехватывает панику в обработчике, пишет её значение и стек в лог и отвечает клиенту 500,
+// чтобы одна ошибка в коде не обрывала соединение без следа в логах
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// ErrAbortHandler — штатный способ прервать ответ, сервер обрабатывает его сам
+			if err, ok := p.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(p)
+			}
+
+			attrs := []any{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"request_id", RequestIDFromContext(r.Context()),
+				"panic", p,
+				"stack", string(debug.Stack()),
+			}
+			slog.Error("Паника при обработке запроса", append(attrs, tracing.LogAttrs(r.Context())...)...)
+
+			response.Error(w, http.StatusInternalServerError, response.CodeInternal, "Ошибка сервера")
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
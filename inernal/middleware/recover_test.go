@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// logBuffer — приёмник JSON-лога, безопасный для записи из горутин сервера
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// entries — разобранные записи лога
+func (b *logBuffer) entries(t *testing.T) []map[string]any {
+	t.Helper()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("запись лога не JSON: %v: %s", err, line)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// captureLogs — направляет лог по умолчанию в буфер до конца теста
+func captureLogs(t *testing.T) *logBuffer {
+	t.Helper()
+
+	logs := &logBuffer{}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return logs
+}
+
+func TestRecoverPanic(t *testing.T) {
+	logs := captureLogs(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /panic", func(http.ResponseWriter, *http.Request) {
+		var user *struct{ Name string }
+		_ = user.Name // разыменование nil, как после неудачного рефакторинга
+	})
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(RequestID(Recover(mux)))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/panic", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(RequestIDHeader, "req-42")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("соединение оборвано паникой: %v", err)
+	}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusInternalServerError || body.Error.Code != response.CodeInternal {
+		t.Fatalf("статус %d, код %q; ожидались 500 и %q", resp.StatusCode, body.Error.Code, response.CodeInternal)
+	}
+
+	// Сервер продолжает обслуживать запросы
+	resp, err = http.Get(server.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("после паники: статус %d", resp.StatusCode)
+	}
+
+	var logged map[string]any
+	for _, entry := range logs.entries(t) {
+		if entry["msg"] == "Паника при обработке запроса" {
+			logged = entry
+		}
+	}
+	if logged == nil {
+		t.Fatal("паника не записана в лог")
+	}
+	if logged["request_id"] != "req-42" || logged["path"] != "/panic" {
+		t.Fatalf("в записи о панике request_id=%v, path=%v", logged["request_id"], logged["path"])
+	}
+	if stack, _ := logged["stack"].(string); !strings.Contains(stack, "recover_test.go") {
+		t.Fatal("в записи о панике нет стека обработчика")
+	}
+	if panicValue, _ := logged["panic"].(string); !strings.Contains(panicValue, "nil pointer") {
+		t.Fatalf("значение паники %v", logged["panic"])
+	}
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	handler := Recover(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	// ErrAbortHandler передаётся серверу как есть: он сам молча обрывает ответ
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Fatalf("перехвачено %v, ожидался http.ErrAbortHandler", p)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader — заголовок, в котором передаётся идентификатор запроса
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength — ограничение длины входящего идентификатора, чтобы клиент не раздувал логи
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID берёт идентификатор запроса из заголовка X-Request-ID или генерирует UUID, если его нет.
+// Идентификатор сохраняется в контексте запроса и возвращается клиенту в заголовке ответа
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		req := r.WithContext(WithRequestID(r.Context(), id))
+		next.ServeHTTP(w, req)
+		// ServeMux записывает шаблон маршрута в копию запроса; возвращаем его внешним middleware
+		r.Pattern = req.Pattern
+	})
+}
+
+// WithRequestID возвращает копию контекста с идентификатором запроса
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext достаёт идентификатор запроса из контекста; пустая строка, если его нет
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// isValidRequestID допускает только непустые идентификаторы разумной длины из печатаемых ASCII-символов,
+// чтобы в логи нельзя было подсунуть переводы строк и управляющие последовательности
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
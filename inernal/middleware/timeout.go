@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// TimeoutOptions — настройки общего дедлайна запроса
+type TimeoutOptions struct {
+	// Timeout — сколько может обрабатываться один запрос
+	Timeout time.Duration
+	// ExemptPaths — пути без дедлайна, например долгоживущие потоки событий
+	ExemptPaths []string
+}
+
+// Timeout ограничивает время обработки запроса, добавляя дедлайн в его контекст. Таймауты операций
+// с базой выводятся из этого же контекста, поэтому срабатывает более близкий из двух дедлайнов.
+// Если обработчик к дедлайну так ничего и не ответил, клиент получает 503.
+// В отличие от http.TimeoutHandler ответ не буферизуется, поэтому потоковые ответы продолжают работать
+func Timeout(opts TimeoutOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(opts.ExemptPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), opts.Timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{ResponseWriter: w}
+			req := r.WithContext(ctx)
+			next.ServeHTTP(tw, req)
+			// ServeMux записывает шаблон маршрута в копию запроса; возвращаем его внешним middleware
+			r.Pattern = req.Pattern
+
+			if !tw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				slog.Warn("Превышено время обработки запроса",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"timeout", opts.Timeout.String(),
+					"request_id", RequestIDFromContext(r.Context()),
+				)
+				response.Error(w, http.StatusServiceUnavailable, response.CodeTimeout, "Превышено время обработки запроса")
+			}
+		})
+	}
+}
+
+// timeoutWriter запоминает, начал ли обработчик ответ, чтобы не отправить второй ответ после дедлайна
+type timeoutWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *timeoutWriter) WriteHeader(status int) {
+	// Информационные 1xx не считаются началом ответа
+	if status >= http.StatusOK {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
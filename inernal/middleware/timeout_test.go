@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/response"
+)
+
+// slowHandler — обработчик, который ждёт delay или отмены контекста запроса и только потом отвечает,
+// если контекст ещё жив. Так ведут себя обработчики, зависящие от базы
+func slowHandler(delay time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+		}
+	})
+}
+
+// serveWithTimeout — прогоняет запрос к path через Timeout с дедлайном 20ms
+func serveWithTimeout(handler http.Handler, path string) *httptest.ResponseRecorder {
+	timeout := Timeout(TimeoutOptions{Timeout: 20 * time.Millisecond, ExemptPaths: []string{"/stream"}})
+	rec := httptest.NewRecorder()
+	timeout(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return rec
+}
+
+func TestTimeoutSlowHandler(t *testing.T) {
+	rec := serveWithTimeout(slowHandler(time.Second), "/api/v1/users")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("статус %d, ожидался 503", rec.Code)
+	}
+
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("ответ не JSON: %v, тело %s", err, rec.Body)
+	}
+	if body.Error.Code != response.CodeTimeout {
+		t.Fatalf("код ошибки %q, ожидался %q", body.Error.Code, response.CodeTimeout)
+	}
+}
+
+func TestTimeoutFastHandler(t *testing.T) {
+	rec := serveWithTimeout(slowHandler(0), "/api/v1/users")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200", rec.Code)
+	}
+}
+
+func TestTimeoutExemptPath(t *testing.T) {
+	rec := serveWithTimeout(slowHandler(50*time.Millisecond), "/stream")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("путь без дедлайна: статус %d, ожидался 200", rec.Code)
+	}
+}
+
+func TestTimeoutAfterResponseStarted(t *testing.T) {
+	// Обработчик успел начать ответ до дедлайна: второй ответ с 503 поверх него не отправляется
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		<-r.Context().Done()
+	})
+
+	rec := serveWithTimeout(handler, "/api/v1/users")
+	if rec.Code != http.StatusAccepted || rec.Body.Len() != 0 {
+		t.Fatalf("статус %d, тело %q: ответ обработчика перезаписан", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,127 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/pressly/goose/v3"
+)
+
+// checksumTable — таблица с контрольными суммами применённых миграций, создаётся одной из миграций.
+// Если в наборе миграций её нет, контрольные суммы не проверяются
+const checksumTable = "migration_checksums"
+
+// ErrChecksumMismatch возвращается, когда файл уже применённой миграции изменился после применения
+var ErrChecksumMismatch = errors.New("файл применённой миграции был изменён")
+
+// verifyChecksums сверяет записанные контрольные суммы с файлами миграций.
+// Пока таблицы нет (миграция с ней ещё не применена), проверять нечего
+func (m *Migrator) verifyChecksums(ctx context.Context, provider *goose.Provider) error {
+	recorded, err := m.recordedChecksums(ctx)
+	if err != nil || recorded == nil {
+		return err
+	}
+
+	for _, source := range provider.ListSources() {
+		expected, ok := recorded[source.Version]
+		if !ok {
+			continue
+		}
+
+		actual, err := m.checksum(source.Path)
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return fmt.Errorf("%w: %s (версия %d)", ErrChecksumMismatch, filepath.Base(source.Path), source.Version)
+		}
+	}
+
+	return nil
+}
+
+// syncChecksums приводит таблицу контрольных сумм в соответствие с применёнными миграциями:
+// записывает суммы новых применённых миграций и удаляет суммы откатанных
+func (m *Migrator) syncChecksums(ctx context.Context, provider *goose.Provider) error {
+	recorded, err := m.recordedChecksums(ctx)
+	if err != nil || recorded == nil {
+		return err
+	}
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, source := range provider.ListSources() {
+		_, isRecorded := recorded[source.Version]
+
+		switch {
+		case applied[source.Version] && !isRecorded:
+			sum, err := m.checksum(source.Path)
+			if err != nil {
+				return err
+			}
+			if _, err := m.db.ExecContext(ctx,
+				`INSERT INTO `+checksumTable+` (version_id, checksum) VALUES (`+
+					m.dialect.placeholder(1)+`, `+m.dialect.placeholder(2)+`)`, source.Version, sum); err != nil {
+				return fmt.Errorf("не удалось записать контрольную сумму миграции %d: %w", source.Version, err)
+			}
+		case !applied[source.Version] && isRecorded:
+			if _, err := m.db.ExecContext(ctx,
+				`DELETE FROM `+checksumTable+` WHERE version_id = `+m.dialect.placeholder(1), source.Version); err != nil {
+				return fmt.Errorf("не удалось удалить контрольную сумму миграции %d: %w", source.Version, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordedChecksums читает записанные контрольные суммы по версиям; nil, если таблицы ещё нет
+func (m *Migrator) recordedChecksums(ctx context.Context) (map[int64]string, error) {
+	exists, err := m.dialect.tableExists(ctx, m.db, checksumTable)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить таблицу контрольных сумм: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version_id, checksum FROM `+checksumTable)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать контрольные суммы миграций: %w", err)
+	}
+	defer rows.Close()
+
+	recorded := make(map[int64]string)
+	for rows.Next() {
+		var version int64
+		var sum string
+		if err := rows.Scan(&version, &sum); err != nil {
+			return nil, fmt.Errorf("не удалось прочитать контрольные суммы миграций: %w", err)
+		}
+		recorded[version] = sum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать контрольные суммы миграций: %w", err)
+	}
+
+	return recorded, nil
+}
+
+// checksum считает SHA-256 содержимого файла миграции
+func (m *Migrator) checksum(path string) (string, error) {
+	content, err := fs.ReadFile(m.migrationsFS(), path)
+	if err != nil {
+		return "", fmt.Errorf("не удалось прочитать миграцию %s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
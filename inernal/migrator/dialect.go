@@ -0,0 +1,108 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/pressly/goose/v3"
+)
+
+// Имена драйверов, для которых мигратор умеет работать со служебными таблицами и блокировкой
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// dialect — особенности СУБД, которые мигратор использует помимо самого goose:
+// синтаксис плейсхолдеров, проверка существования таблицы и межпроцессная блокировка.
+// DDL таблицы версий goose создаёт сам по своему диалекту
+type dialect interface {
+	// gooseDialect — диалект goose для таблицы версий и выполнения миграций
+	gooseDialect() goose.Dialect
+	// placeholder — параметр запроса с номером n, начиная с 1
+	placeholder(n int) string
+	// tableExists сообщает, есть ли в текущей схеме таблица с именем table
+	tableExists(ctx context.Context, db *sql.DB, table string) (bool, error)
+	// tryLock пытается взять блокировку миграций на соединении conn, не дожидаясь её
+	tryLock(ctx context.Context, conn *sql.Conn) (bool, error)
+	// unlock снимает блокировку, взятую tryLock на том же соединении
+	unlock(ctx context.Context, conn *sql.Conn) error
+}
+
+// dialectFor выбирает диалект по имени драйвера
+func dialectFor(driver string) (dialect, error) {
+	switch driver {
+	case DriverPostgres:
+		return postgresDialect{}, nil
+	case DriverMySQL:
+		return mysqlDialect{}, nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый драйвер базы данных %q", driver)
+	}
+}
+
+// advisoryLockKey — ключ advisory-блокировки Postgres, общий для всех экземпляров сервиса
+const advisoryLockKey int64 = 7_301_202_405
+
+// postgresDialect — Postgres: плейсхолдеры $n и сессионные advisory-блокировки
+type postgresDialect struct{}
+
+func (postgresDialect) gooseDialect() goose.Dialect {
+	return goose.DialectPostgres
+}
+
+func (postgresDialect) placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists)
+	return exists, err
+}
+
+func (postgresDialect) tryLock(ctx context.Context, conn *sql.Conn) (bool, error) {
+	var locked bool
+	err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, advisoryLockKey).Scan(&locked)
+	return locked, err
+}
+
+func (postgresDialect) unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, advisoryLockKey)
+	return err
+}
+
+// mysqlLockName — имя именованной блокировки MySQL, общее для всех экземпляров сервиса
+const mysqlLockName = "docker-compose-tutorial:migrations"
+
+// mysqlDialect — MySQL: плейсхолдеры ? и именованные блокировки GET_LOCK
+type mysqlDialect struct{}
+
+func (mysqlDialect) gooseDialect() goose.Dialect {
+	return goose.DialectMySQL
+}
+
+func (mysqlDialect) placeholder(int) string {
+	return "?"
+}
+
+func (mysqlDialect) tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?`, table).Scan(&count)
+	return count > 0, err
+}
+
+func (mysqlDialect) tryLock(ctx context.Context, conn *sql.Conn) (bool, error) {
+	// GET_LOCK с нулевым таймаутом возвращает 1, если блокировка взята, и 0, если она занята
+	var locked sql.NullInt64
+	err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, 0)`, mysqlLockName).Scan(&locked)
+	return locked.Valid && locked.Int64 == 1, err
+}
+
+func (mysqlDialect) unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, mysqlLockName)
+	return err
+}
@@ -0,0 +1,69 @@
+package migrator
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// embeddedMigrations — миграции из testdata, встроенные в тестовый бинарник так же, как migrations.FS в сервисе
+//
+//go:embed testdata/migrations/*.sql
+var embeddedMigrations embed.FS
+
+// embeddedMigrationsFS — встроенные миграции с файлами в корне, как того требует NewMigratorFS
+func embeddedMigrationsFS(t *testing.T) fs.FS {
+	t.Helper()
+
+	fsys, err := fs.Sub(embeddedMigrations, "testdata/migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fsys
+}
+
+func TestNewMigratorFSEmbedded(t *testing.T) {
+	m := NewMigratorFS(testDB(t), embeddedMigrationsFS(t))
+	// Конструктор рассчитан на Postgres; для проверки без внешней базы подменяем только диалект
+	m.dialect = sqliteDialect{mu: &sync.Mutex{}}
+
+	applied, err := m.Apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[1].Name != "002_add_notes_title.sql" {
+		t.Fatalf("применены %+v, ожидались обе встроенные миграции", applied)
+	}
+	expectVersion(t, m, 2)
+	expectTable(t, m, "notes", true)
+}
+
+func TestNewMigratorDir(t *testing.T) {
+	// Прежний конструктор с путём к директории продолжает работать на тех же файлах
+	dir := t.TempDir()
+	entries, err := fs.ReadDir(embeddedMigrationsFS(t), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := fs.ReadFile(embeddedMigrationsFS(t), entry.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, entry.Name()), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := NewMigrator(testDB(t), dir)
+	m.dialect = sqliteDialect{mu: &sync.Mutex{}}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 2)
+	expectTable(t, m, "notes", true)
+}
@@ -0,0 +1,107 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/pressly/goose/v3"
+)
+
+// ErrUnknownVersion возвращается, когда ForceVersion вызван с версией, которой нет среди миграций
+var ErrUnknownVersion = errors.New("миграции с такой версией нет")
+
+// ForceVersion записывает в таблицу версий состояние "применены ровно миграции до version включительно",
+// не выполняя их SQL. Нужен, чтобы восстановиться после сбоя посередине миграции без транзакции:
+// оператор вручную доводит схему до нужного состояния и фиксирует версию, после чего Up продолжает работу.
+// Операция опасная: при ошибке в выборе версии мигратор пропустит или повторит миграции
+func (m *Migrator) ForceVersion(version int64) error {
+	provider, err := m.provider()
+	if err != nil {
+		return err
+	}
+
+	if version != 0 && !hasSource(provider, version) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	ctx := context.Background()
+
+	return m.withLock(ctx, func() error {
+		// GetDBVersion создаёт таблицу версий, если её ещё нет
+		previous, err := provider.GetDBVersion(ctx)
+		if err != nil {
+			return fmt.Errorf("не удалось получить текущую версию схемы: %w", err)
+		}
+
+		slog.Warn("ПРИНУДИТЕЛЬНАЯ УСТАНОВКА ВЕРСИИ СХЕМЫ: SQL миграций не выполняется, "+
+			"состояние базы должно быть приведено к этой версии вручную",
+			"from_version", previous, "to_version", version)
+
+		if err := m.forceVersion(ctx, provider, version); err != nil {
+			return err
+		}
+
+		// Контрольные суммы приводим к новому набору применённых миграций
+		if err := m.syncChecksums(ctx, provider); err != nil {
+			return err
+		}
+
+		slog.Warn("Версия схемы установлена принудительно", "version", version)
+		return nil
+	})
+}
+
+// forceVersion переписывает таблицу версий в одной транзакции: удаляет записи о миграциях новее version
+// и добавляет недостающие записи о более старых
+func (m *Migrator) forceVersion(ctx context.Context, provider *goose.Provider, version int64) (err error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Нулевая запись goose появляется при создании таблицы и остаётся на месте
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM `+goose.DefaultTablename+` WHERE version_id > `+m.dialect.placeholder(1), version); err != nil {
+		return fmt.Errorf("не удалось удалить записи о миграциях новее %d: %w", version, err)
+	}
+
+	for _, source := range provider.ListSources() {
+		if source.Version > version || applied[source.Version] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO `+goose.DefaultTablename+` (version_id, is_applied) VALUES (`+
+				m.dialect.placeholder(1)+`, true)`, source.Version); err != nil {
+			return fmt.Errorf("не удалось записать миграцию %d как применённую: %w", source.Version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("не удалось зафиксировать версию схемы: %w", err)
+	}
+
+	return nil
+}
+
+// hasSource сообщает, есть ли среди миграций миграция с версией version
+func hasSource(provider *goose.Provider, version int64) bool {
+	for _, source := range provider.ListSources() {
+		if source.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
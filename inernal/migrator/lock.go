@@ -0,0 +1,63 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// lockTimeout — сколько ждать, пока другой экземпляр закончит миграции
+	lockTimeout = time.Minute
+	// lockRetryInterval — пауза между попытками взять блокировку
+	lockRetryInterval = time.Second
+)
+
+// ErrLockTimeout возвращается, когда блокировку миграций не удалось получить за lockTimeout
+var ErrLockTimeout = errors.New("не удалось получить блокировку миграций")
+
+// withLock выполняет fn под межпроцессной блокировкой базы, чтобы одновременно запущенные
+// экземпляры не применяли миграции параллельно: остальные ждут, пока первый закончит.
+// Блокировка сессионная, поэтому держится на отдельном соединении до окончания fn
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось получить соединение для блокировки миграций: %w", err)
+	}
+	defer conn.Close()
+
+	lockCtx, cancel := context.WithTimeout(ctx, lockTimeout)
+	defer cancel()
+
+	for {
+		locked, err := m.dialect.tryLock(lockCtx, conn)
+		if err != nil {
+			if lockCtx.Err() != nil {
+				return fmt.Errorf("%w за %s", ErrLockTimeout, lockTimeout)
+			}
+			return fmt.Errorf("ошибка при получении блокировки миграций: %w", err)
+		}
+		if locked {
+			break
+		}
+
+		slog.Info("Миграции выполняет другой экземпляр, ждём освобождения блокировки")
+
+		select {
+		case <-lockCtx.Done():
+			return fmt.Errorf("%w за %s", ErrLockTimeout, lockTimeout)
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	defer func() {
+		// Снимаем блокировку, даже если контекст уже отменён
+		if err := m.dialect.unlock(context.WithoutCancel(ctx), conn); err != nil {
+			slog.Error("Ошибка снятия блокировки миграций", "error", err)
+		}
+	}()
+
+	return fn()
+}
@@ -1,28 +1,511 @@
 package migrator
 
 import (
+	"bufio"
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/pressly/goose/v3"
 )
 
+var (
+	// ErrNoAppliedMigrations возвращается при попытке откатить миграции, когда ни одна ещё не применена
+	ErrNoAppliedMigrations = errors.New("нет применённых миграций для отката")
+	// ErrStepsOutOfRange возвращается, когда Steps вышел бы за первую или последнюю миграцию
+	ErrStepsOutOfRange = errors.New("запрошено шагов больше, чем доступно миграций")
+)
+
+// MigrationStatus — состояние одной миграции: применена она или ещё ожидает применения
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// noTransactionAnnotation — аннотация goose, отключающая транзакцию для миграции,
+// например ради CREATE INDEX CONCURRENTLY
+const noTransactionAnnotation = "-- +goose NO TRANSACTION"
+
+// PlannedMigration — миграция, которую применил бы Up, вместе с SQL её секции Up
+type PlannedMigration struct {
+	Version int64
+	Name    string
+	SQL     string
+	// Transactional — миграция и запись её версии выполняются в одной транзакции
+	Transactional bool
+}
+
 type Migrator struct {
 	db            *sql.DB
 	migrationsDir string
+	fsys          fs.FS
+	dialect       dialect
+	recorder      Recorder
 }
 
 func NewMigrator(db *sql.DB, migrationsDir string) *Migrator {
 	return &Migrator{
 		db:            db,
 		migrationsDir: migrationsDir,
+		fsys:          os.DirFS(migrationsDir),
+		dialect:       postgresDialect{},
+	}
+}
+
+// NewMigratorFS создаёт мигратор для Postgres, читающий миграции из fsys, например из embed.FS.
+// Файлы миграций должны лежать в корне fsys
+func NewMigratorFS(db *sql.DB, fsys fs.FS) *Migrator {
+	return &Migrator{
+		db:            db,
+		migrationsDir: "встроенные миграции",
+		fsys:          fsys,
+		dialect:       postgresDialect{},
+	}
+}
+
+// NewMigratorForDriver создаёт мигратор для СУБД, выбранной по имени драйвера: DriverPostgres или DriverMySQL
+func NewMigratorForDriver(db *sql.DB, driver string, fsys fs.FS) (*Migrator, error) {
+	d, err := dialectFor(driver)
+	if err != nil {
+		return nil, err
 	}
+
+	return &Migrator{
+		db:            db,
+		migrationsDir: "миграции " + driver,
+		fsys:          fsys,
+		dialect:       d,
+	}, nil
+}
+
+// AppliedMigration — миграция, применённая вызовом Apply
+type AppliedMigration struct {
+	Version  int64
+	Name     string
+	Duration time.Duration
 }
 
+// Up применяет все ожидающие миграции по порядку. Каждая миграция выполняется в своей транзакции
+// вместе с записью версии: если миграция падает посередине, она откатывается целиком, а версия
+// остаётся на последней успешной. Миграции с аннотацией "-- +goose NO TRANSACTION" выполняются
+// без транзакции, и при сбое их приходится доводить вручную
 func (m *Migrator) Up() error {
-	err := goose.Up(m.db, m.migrationsDir)
+	_, err := m.Apply()
+	return err
+}
+
+// Apply работает как Up и под той же блокировкой, но возвращает применённые миграции по порядку.
+// Пустой список означает, что схема уже актуальна
+func (m *Migrator) Apply() ([]AppliedMigration, error) {
+	provider, err := m.provider()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	var applied []AppliedMigration
+	// Проверка и применение идут под блокировкой: другой экземпляр мог начать миграции раньше
+	err = m.withLock(ctx, func() error {
+		// Не применяем новые миграции поверх тех, что отредактированы после применения
+		err := m.verifyChecksums(ctx, provider)
+		if err != nil {
+			return err
+		}
+
+		pending, err := m.pending(ctx, provider)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			if !migration.Transactional {
+				slog.Warn("Миграция выполняется без транзакции и при сбое не откатится",
+					"version", migration.Version, "name", migration.Name)
+			}
+		}
+
+		start := time.Now()
+		results, err := provider.Up(ctx)
+		m.recordUp(results, err, time.Since(start))
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			applied = append(applied, AppliedMigration{
+				Version:  result.Source.Version,
+				Name:     filepath.Base(result.Source.Path),
+				Duration: result.Duration,
+			})
+		}
+
+		return m.syncChecksums(ctx, provider)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// DryRun возвращает по порядку миграции, которые применил бы Up, и логирует их SQL.
+// Миграции не выполняются, а служебная таблица версий не создаётся и не меняется, поэтому вызывать
+// DryRun безопасно на рабочей базе: он только читает состояние схемы
+func (m *Migrator) DryRun() ([]PlannedMigration, error) {
+	provider, err := m.provider()
+	if err != nil {
+		return nil, err
+	}
+
+	planned, err := m.pending(context.Background(), provider)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range planned {
+		slog.Info("Миграция будет применена",
+			"version", migration.Version,
+			"name", migration.Name,
+			"transactional", migration.Transactional,
+			"sql", migration.SQL,
+		)
+	}
+
+	return planned, nil
+}
+
+// Pending возвращает по возрастанию версии миграции, которые есть в директории, но ещё не применены.
+// Как и DryRun, только читает таблицу версий и не создаёт её; в отличие от Status не собирает
+// состояние всех миграций
+func (m *Migrator) Pending() ([]PlannedMigration, error) {
+	provider, err := m.provider()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.pending(context.Background(), provider)
+}
+
+// PendingVersions возвращает по возрастанию версии ещё не применённых миграций. В отличие от Pending
+// не читает файлы миграций, поэтому подходит для частых проверок, например readiness-пробы
+func (m *Migrator) PendingVersions(ctx context.Context) ([]int64, error) {
+	provider, err := m.provider()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int64
+	for _, source := range provider.ListSources() {
+		if !applied[source.Version] {
+			versions = append(versions, source.Version)
+		}
+	}
+
+	return versions, nil
+}
+
+// pending возвращает по порядку ещё не применённые миграции, ничего не меняя в базе
+func (m *Migrator) pending(ctx context.Context, provider *goose.Provider) ([]PlannedMigration, error) {
+	// provider.Status создал бы таблицу версий на чистой базе, поэтому читаем её напрямую
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var planned []PlannedMigration
+	for _, source := range provider.ListSources() {
+		if applied[source.Version] {
+			continue
+		}
+
+		// Путь источника указан относительно файловой системы миграций
+		content, err := fs.ReadFile(m.migrationsFS(), source.Path)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать миграцию %s: %w", source.Path, err)
+		}
+
+		planned = append(planned, PlannedMigration{
+			Version:       source.Version,
+			Name:          filepath.Base(source.Path),
+			SQL:           upSection(string(content)),
+			Transactional: !hasNoTransaction(string(content)),
+		})
+	}
+
+	return planned, nil
+}
+
+// Down откатывает все применённые миграции в обратном порядке
+func (m *Migrator) Down() error {
+	provider, err := m.provider()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	return m.withLock(ctx, func() error {
+		err := ensureApplied(provider)
+		if err != nil {
+			return err
+		}
+
+		_, err = provider.DownTo(ctx, 0)
+		if err != nil {
+			return err
+		}
+
+		return m.syncChecksums(ctx, provider)
+	})
+}
+
+// DownOne откатывает только последнюю применённую миграцию
+func (m *Migrator) DownOne() error {
+	provider, err := m.provider()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	return m.withLock(ctx, func() error {
+		err := ensureApplied(provider)
+		if err != nil {
+			return err
+		}
+
+		_, err = provider.Down(ctx)
+		if err != nil {
+			return err
+		}
+
+		return m.syncChecksums(ctx, provider)
+	})
+}
+
+// Steps применяет ровно n миграций вверх при положительном n или откатывает |n| миграций при отрицательном.
+// Если столько миграций применить или откатить нельзя, ничего не выполняется и возвращается ErrStepsOutOfRange
+func (m *Migrator) Steps(n int) error {
+	if n == 0 {
+		return nil
+	}
+
+	provider, err := m.provider()
 	if err != nil {
 		return err
 	}
 
+	ctx := context.Background()
+
+	return m.withLock(ctx, func() error {
+		return m.steps(ctx, provider, n)
+	})
+}
+
+// steps выполняет Steps; вызывается под блокировкой миграций
+func (m *Migrator) steps(ctx context.Context, provider *goose.Provider, n int) error {
+	// Считаем применённые и ожидающие миграции, чтобы проверить границы до того, как что-либо менять
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось получить состояние миграций: %w", err)
+	}
+
+	var applied, pending int
+	for _, status := range statuses {
+		if status.State == goose.StateApplied {
+			applied++
+		} else {
+			pending++
+		}
+	}
+
+	if n > 0 {
+		if n > pending {
+			return fmt.Errorf("%w: вверх %d, ожидают применения %d", ErrStepsOutOfRange, n, pending)
+		}
+		if err := m.verifyChecksums(ctx, provider); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		var results []*goose.MigrationResult
+		for range n {
+			result, err := provider.UpByOne(ctx)
+			if result != nil {
+				results = append(results, result)
+			}
+			if err != nil {
+				m.recordUp(results, err, time.Since(start))
+				return err
+			}
+		}
+		m.recordUp(results, nil, time.Since(start))
+
+		return m.syncChecksums(ctx, provider)
+	}
+
+	if -n > applied {
+		return fmt.Errorf("%w: вниз %d, применено %d", ErrStepsOutOfRange, -n, applied)
+	}
+
+	for range -n {
+		if _, err := provider.Down(ctx); err != nil {
+			return err
+		}
+	}
+
+	return m.syncChecksums(ctx, provider)
+}
+
+// Version возвращает текущую версию схемы — номер последней применённой миграции, 0 если ни одной
+func (m *Migrator) Version() (int64, error) {
+	provider, err := m.provider()
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := provider.GetDBVersion(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("не удалось получить текущую версию схемы: %w", err)
+	}
+
+	return version, nil
+}
+
+// Status возвращает все миграции из директории по возрастанию версии с отметкой, применены ли они.
+// Данные берутся из служебной таблицы goose, в которой мигратор ведёт учёт версий
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	provider, err := m.provider()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := provider.Status(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить состояние миграций: %w", err)
+	}
+
+	result := make([]MigrationStatus, 0, len(statuses))
+	for _, status := range statuses {
+		result = append(result, MigrationStatus{
+			Version:   status.Source.Version,
+			Name:      filepath.Base(status.Source.Path),
+			Applied:   status.State == goose.StateApplied,
+			AppliedAt: status.AppliedAt,
+		})
+	}
+
+	return result, nil
+}
+
+// provider создаёт goose.Provider поверх базы и миграций, переданных в NewMigrator или NewMigratorFS
+func (m *Migrator) provider() (*goose.Provider, error) {
+	provider, err := goose.NewProvider(m.dialect.gooseDialect(), m.db, m.migrationsFS(), goose.WithVerbose(true))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить миграции из %s: %w", m.migrationsDir, err)
+	}
+
+	return provider, nil
+}
+
+// appliedVersions читает применённые версии из служебной таблицы goose, не создавая её.
+// Если таблицы ещё нет, ни одна миграция не применена
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]bool, error) {
+	exists, err := m.dialect.tableExists(ctx, m.db, goose.DefaultTablename)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить таблицу версий миграций: %w", err)
+	}
+
+	applied := make(map[int64]bool)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version_id FROM `+goose.DefaultTablename+` WHERE is_applied`)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать применённые миграции: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("не удалось прочитать применённые миграции: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать применённые миграции: %w", err)
+	}
+
+	return applied, nil
+}
+
+// migrationsFS возвращает файловую систему с миграциями
+func (m *Migrator) migrationsFS() fs.FS {
+	return m.fsys
+}
+
+// upSection вырезает из файла миграции SQL между аннотациями "-- +goose Up" и "-- +goose Down".
+// Остальные аннотации goose отбрасываются, комментарии и сами запросы сохраняются как есть
+func upSection(content string) string {
+	var lines []string
+	inUp := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		annotation, isAnnotation := strings.CutPrefix(strings.TrimSpace(line), "-- +goose ")
+		if isAnnotation {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				inUp = true
+			case "Down":
+				inUp = false
+			}
+			continue
+		}
+		if inUp {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// hasNoTransaction сообщает, отключена ли транзакция для миграции аннотацией NO TRANSACTION
+func hasNoTransaction(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == noTransactionAnnotation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ensureApplied проверяет, что в базе есть хотя бы одна применённая миграция
+func ensureApplied(provider *goose.Provider) error {
+	version, err := provider.GetDBVersion(context.Background())
+	if err != nil {
+		return fmt.Errorf("не удалось получить текущую версию схемы: %w", err)
+	}
+	if version == 0 {
+		return ErrNoAppliedMigrations
+	}
+
 	return nil
 }
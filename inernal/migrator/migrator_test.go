@@ -0,0 +1,569 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestMain(m *testing.M) {
+	// Мигратор и goose подробно логируют каждую миграцию; в выводе тестов это только мешает
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	goose.SetLogger(goose.NopLogger())
+	os.Exit(m.Run())
+}
+
+// expectVersion — проверяет текущую версию схемы
+func expectVersion(t *testing.T, m *Migrator, want int64) {
+	t.Helper()
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != want {
+		t.Fatalf("версия схемы %d, ожидалась %d", version, want)
+	}
+}
+
+// expectTable — проверяет, есть ли в базе таблица
+func expectTable(t *testing.T, m *Migrator, table string, want bool) {
+	t.Helper()
+
+	exists, err := m.dialect.tableExists(context.Background(), m.db, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists != want {
+		t.Fatalf("таблица %s: exists=%v, ожидалось %v", table, exists, want)
+	}
+}
+
+// plannedVersions — версии запланированных миграций по порядку
+func plannedVersions(planned []PlannedMigration) []int64 {
+	versions := make([]int64, 0, len(planned))
+	for _, migration := range planned {
+		versions = append(versions, migration.Version)
+	}
+
+	return versions
+}
+
+func TestMigratorApplyAndDown(t *testing.T) {
+	m := newSQLiteMigrator(testDB(t), testMigrations(), &sync.Mutex{})
+
+	applied, err := m.Apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int64
+	for _, migration := range applied {
+		versions = append(versions, migration.Version)
+	}
+	if !slices.Equal(versions, []int64{1, 2, 3, 4}) {
+		t.Fatalf("применены версии %v, ожидались 1–4", versions)
+	}
+	if applied[0].Name != "001_create_accounts.sql" {
+		t.Fatalf("имя первой миграции %q", applied[0].Name)
+	}
+	expectVersion(t, m, 4)
+
+	// Схема действительно изменена: новая колонка доступна
+	if _, err := m.db.Exec(`INSERT INTO accounts (email, name) VALUES (?, ?)`, "alice@example.com", "Alice"); err != nil {
+		t.Fatalf("колонка из миграции 3 недоступна: %v", err)
+	}
+
+	// Повторный запуск ничего не применяет
+	applied, err = m.Apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("повторный Apply применил %d миграций", len(applied))
+	}
+
+	if err := m.Down(); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 0)
+	expectTable(t, m, "accounts", false)
+
+	if err := m.Down(); !errors.Is(err, ErrNoAppliedMigrations) {
+		t.Fatalf("Down без применённых миграций вернул %v, ожидался ErrNoAppliedMigrations", err)
+	}
+}
+
+// schemaObjects — определения всех объектов схемы, кроме служебной таблицы версий goose
+func schemaObjects(t *testing.T, m *Migrator) []string {
+	t.Helper()
+
+	rows, err := m.db.Query(`SELECT type || ' ' || name || ': ' || COALESCE(sql, '') FROM sqlite_master
+		WHERE name <> ? AND name NOT LIKE 'sqlite_%' ORDER BY type, name`, goose.DefaultTablename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var objects []string
+	for rows.Next() {
+		var object string
+		if err := rows.Scan(&object); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, object)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return objects
+}
+
+func TestMigratorDownRestoresSchema(t *testing.T) {
+	m := newSQLiteMigrator(testDB(t), testMigrations(), &sync.Mutex{})
+
+	// Объект, созданный до миграций, должен пережить Up и Down без изменений
+	if _, err := m.db.Exec(`CREATE TABLE existing (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	before := schemaObjects(t, m)
+
+	if _, err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if slices.Equal(schemaObjects(t, m), before) {
+		t.Fatal("Up не изменил схему")
+	}
+
+	// DownOne откатывает только последнюю миграцию: индекс удалён, колонка на месте
+	if err := m.DownOne(); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 3)
+	if _, err := m.db.Exec(`SELECT name FROM accounts`); err != nil {
+		t.Fatalf("DownOne откатил больше одной миграции: %v", err)
+	}
+
+	if err := m.Down(); err != nil {
+		t.Fatal(err)
+	}
+	if after := schemaObjects(t, m); !slices.Equal(after, before) {
+		t.Fatalf("после Up и Down схема %v, до миграций была %v", after, before)
+	}
+}
+
+func TestMigratorSteps(t *testing.T) {
+	m := newSQLiteMigrator(testDB(t), testMigrations(), &sync.Mutex{})
+
+	// По одной миграции вверх и обратно: схема меняется вместе с версией
+	if err := m.Steps(1); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 1)
+	expectTable(t, m, "accounts", true)
+	if err := m.Steps(-1); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 0)
+	expectTable(t, m, "accounts", false)
+
+	// За первую миграцию откатиться нельзя
+	if err := m.Steps(-1); !errors.Is(err, ErrStepsOutOfRange) {
+		t.Fatalf("Steps(-1) на пустой схеме вернул %v, ожидался ErrStepsOutOfRange", err)
+	}
+
+	if err := m.Steps(2); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 2)
+
+	// За границы выходить нельзя, и версия при этом не меняется
+	if err := m.Steps(3); !errors.Is(err, ErrStepsOutOfRange) {
+		t.Fatalf("Steps(3) вернул %v, ожидался ErrStepsOutOfRange", err)
+	}
+	if err := m.Steps(-3); !errors.Is(err, ErrStepsOutOfRange) {
+		t.Fatalf("Steps(-3) вернул %v, ожидался ErrStepsOutOfRange", err)
+	}
+	expectVersion(t, m, 2)
+
+	if err := m.Steps(-1); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 1)
+
+	if err := m.DownOne(); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 0)
+}
+
+// expectStatus — проверяет, что Status перечисляет все миграции и applied совпадает с ожидаемым для каждой
+func expectStatus(t *testing.T, m *Migrator, applied func(version int64) bool) {
+	t.Helper()
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 4 {
+		t.Fatalf("Status вернул %d миграций, ожидалось 4", len(statuses))
+	}
+	for _, status := range statuses {
+		if status.Applied != applied(status.Version) {
+			t.Fatalf("миграция %d (%s): Applied=%v", status.Version, status.Name, status.Applied)
+		}
+		if status.Applied == status.AppliedAt.IsZero() {
+			t.Fatalf("миграция %d: Applied=%v, AppliedAt=%v", status.Version, status.Applied, status.AppliedAt)
+		}
+	}
+}
+
+func TestMigratorStatus(t *testing.T) {
+	m := newSQLiteMigrator(testDB(t), testMigrations(), &sync.Mutex{})
+
+	// До Up все миграции ожидают применения
+	expectVersion(t, m, 0)
+	expectStatus(t, m, func(int64) bool { return false })
+
+	if _, err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 4)
+	expectStatus(t, m, func(int64) bool { return true })
+}
+
+func TestMigratorDryRunAndPending(t *testing.T) {
+	m := newSQLiteMigrator(testDB(t), testMigrations(), &sync.Mutex{})
+	before := schemaObjects(t, m)
+
+	planned, err := m.DryRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(plannedVersions(planned), []int64{1, 2, 3, 4}) {
+		t.Fatalf("запланированы версии %v, ожидались 1–4", plannedVersions(planned))
+	}
+	if planned[2].SQL != "ALTER TABLE accounts ADD COLUMN name TEXT NOT NULL DEFAULT '';" {
+		t.Fatalf("SQL секции Up: %q", planned[2].SQL)
+	}
+	if !planned[0].Transactional || planned[3].Transactional {
+		t.Fatal("признак транзакции определён неверно: миграция 4 помечена NO TRANSACTION")
+	}
+
+	// DryRun только читает состояние: ни схема, ни таблица версий goose не создаются
+	expectTable(t, m, goose.DefaultTablename, false)
+	if after := schemaObjects(t, m); !slices.Equal(after, before) {
+		t.Fatalf("DryRun изменил схему: %v", after)
+	}
+
+	if err := m.Steps(2); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(plannedVersions(pending), []int64{3, 4}) {
+		t.Fatalf("ожидают применения %v, ожидались 3 и 4", plannedVersions(pending))
+	}
+
+	// На частично применённой схеме DryRun планирует только оставшиеся миграции и ничего не применяет
+	before = schemaObjects(t, m)
+	planned, err = m.DryRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(plannedVersions(planned), []int64{3, 4}) {
+		t.Fatalf("DryRun запланировал %v, ожидались 3 и 4", plannedVersions(planned))
+	}
+	expectVersion(t, m, 2)
+	if after := schemaObjects(t, m); !slices.Equal(after, before) {
+		t.Fatalf("DryRun изменил схему: было %v, стало %v", before, after)
+	}
+	versions, err := m.PendingVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(versions, []int64{3, 4}) {
+		t.Fatalf("PendingVersions = %v, ожидались 3 и 4", versions)
+	}
+
+	expectStatus(t, m, func(version int64) bool { return version <= 2 })
+}
+
+func TestMigratorPending(t *testing.T) {
+	m := newSQLiteMigrator(testDB(t), testMigrations(), &sync.Mutex{})
+
+	// На чистой базе ожидают все миграции по порядку, а таблица версий не создаётся
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(pending))
+	for _, migration := range pending {
+		names = append(names, migration.Name)
+	}
+	want := []string{"001_create_accounts.sql", "002_create_migration_checksums.sql",
+		"003_add_accounts_name.sql", "004_add_accounts_name_index.sql"}
+	if !slices.Equal(names, want) || !slices.Equal(plannedVersions(pending), []int64{1, 2, 3, 4}) {
+		t.Fatalf("ожидают применения %v (%v), ожидались %v", names, plannedVersions(pending), want)
+	}
+	expectTable(t, m, goose.DefaultTablename, false)
+
+	// После Up ожидающих миграций не остаётся
+	if _, err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("после Up ожидают применения %v", plannedVersions(pending))
+	}
+}
+
+func TestMigratorChecksumMismatch(t *testing.T) {
+	db := testDB(t)
+	fsys := testMigrations()
+	m := newSQLiteMigrator(db, fsys, &sync.Mutex{})
+
+	if err := m.Steps(3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Применённую миграцию отредактировали задним числом: новые миграции поверх неё не применяются,
+	// а ошибка называет изменённый файл
+	original := fsys["001_create_accounts.sql"].Data
+	fsys["001_create_accounts.sql"].Data = append(slices.Clone(original), []byte("-- правка\n")...)
+	_, err := m.Apply()
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Apply вернул %v, ожидался ErrChecksumMismatch", err)
+	}
+	if !strings.Contains(err.Error(), "001_create_accounts.sql") {
+		t.Fatalf("ошибка %q не называет изменённую миграцию", err)
+	}
+	expectVersion(t, m, 3)
+
+	// После отката правки миграции применяются как обычно. Правка ещё не применённой миграции —
+	// обычная разработка, а не подмена
+	fsys["001_create_accounts.sql"].Data = original
+	fsys["004_add_accounts_name_index.sql"].Data = append(fsys["004_add_accounts_name_index.sql"].Data, []byte("-- правка\n")...)
+	if _, err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 4)
+}
+
+func TestMigratorFailedMigrationRollsBack(t *testing.T) {
+	fsys := testMigrations()
+	fsys["003_add_accounts_name.sql"].Data = []byte(`-- +goose Up
+ALTER TABLE accounts ADD COLUMN name TEXT;
+ALTER TABLE missing_table ADD COLUMN name TEXT;
+
+-- +goose Down
+ALTER TABLE accounts DROP COLUMN name;
+`)
+	m := newSQLiteMigrator(testDB(t), fsys, &sync.Mutex{})
+	recorder := &testRecorder{}
+	m.SetRecorder(recorder)
+
+	if _, err := m.Apply(); err == nil {
+		t.Fatal("Apply с ошибочной миграцией завершился без ошибки")
+	}
+	// Упавшая миграция откатилась целиком вместе с первой половиной, версия осталась на последней успешной
+	expectVersion(t, m, 2)
+	if _, err := m.db.Exec(`SELECT name FROM accounts`); err == nil {
+		t.Fatal("изменения упавшей миграции не откатились")
+	}
+
+	if !slices.Equal(recorder.versions, []int64{1, 2, 3}) || recorder.failed != 1 {
+		t.Fatalf("записаны версии %v, неудач %d; ожидались 1–3 и одна неудача", recorder.versions, recorder.failed)
+	}
+}
+
+func TestMigratorRecordsEachMigration(t *testing.T) {
+	m := newSQLiteMigrator(testDB(t), testMigrations(), &sync.Mutex{})
+	recorder := &testRecorder{}
+	m.SetRecorder(recorder)
+
+	var logs bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil))) })
+
+	if _, err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(recorder.versions, []int64{1, 2, 3, 4}) || recorder.failed != 0 {
+		t.Fatalf("записаны версии %v, неудач %d; ожидались 1–4 без неудач", recorder.versions, recorder.failed)
+	}
+	if !strings.Contains(logs.String(), `msg="Применение миграций завершено" applied=4`) {
+		t.Fatalf("в логе нет итога применения миграций:\n%s", logs.String())
+	}
+
+	// Повторный запуск ничего не применяет и ничего не записывает
+	if _, err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.versions) != 4 {
+		t.Fatalf("после повторного Apply записаны версии %v", recorder.versions)
+	}
+}
+
+func TestMigratorForceVersion(t *testing.T) {
+	m := newSQLiteMigrator(testDB(t), testMigrations(), &sync.Mutex{})
+
+	if err := m.ForceVersion(99); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("ForceVersion(99) вернул %v, ожидался ErrUnknownVersion", err)
+	}
+
+	// Версия фиксируется без выполнения SQL миграций
+	if err := m.ForceVersion(2); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 2)
+	expectTable(t, m, "accounts", false)
+
+	if err := m.ForceVersion(1); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 1)
+}
+
+func TestMigratorForceVersionRecoversFromFailure(t *testing.T) {
+	// Миграция без транзакции падает на втором выражении: первое уже применено, а версия не записана
+	fsys := testMigrations()
+	fsys["003_add_accounts_name.sql"].Data = []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+ALTER TABLE accounts ADD COLUMN name TEXT NOT NULL DEFAULT '';
+ALTER TABLE missing_table ADD COLUMN name TEXT;
+
+-- +goose Down
+ALTER TABLE accounts DROP COLUMN name;
+`)
+	m := newSQLiteMigrator(testDB(t), fsys, &sync.Mutex{})
+
+	if _, err := m.Apply(); err == nil {
+		t.Fatal("Apply с ошибочной миграцией завершился без ошибки")
+	}
+	expectVersion(t, m, 2)
+	if _, err := m.db.Exec(`SELECT name FROM accounts`); err != nil {
+		t.Fatalf("первое выражение миграции без транзакции не применилось: %v", err)
+	}
+
+	// Повторный запуск упирается в уже добавленную колонку: без вмешательства не продвинуться
+	if _, err := m.Apply(); err == nil {
+		t.Fatal("повторный Apply поверх частично применённой миграции прошёл")
+	}
+	expectVersion(t, m, 2)
+
+	// Оператор убедился, что схема соответствует версии 3, и фиксирует её
+	if err := m.ForceVersion(3); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 3)
+
+	applied, err := m.Apply()
+	if err != nil {
+		t.Fatalf("Apply после ForceVersion: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 4 {
+		t.Fatalf("применены %+v, ожидалась только 4", applied)
+	}
+	expectVersion(t, m, 4)
+}
+
+func TestMigratorConcurrentApply(t *testing.T) {
+	db := testDB(t)
+	mu := &sync.Mutex{}
+
+	// Экземпляры сервиса, запущенные одновременно: миграции применяет только один, второй ждёт его
+	var wg sync.WaitGroup
+	total := make([]int, 2)
+	errs := make([]error, 2)
+	for i := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied, err := newSQLiteMigrator(db, testMigrations(), mu).Apply()
+			total[i], errs[i] = len(applied), err
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if total[0]+total[1] != 4 {
+		t.Fatalf("применено миграций %v, ожидалось всего четыре", total)
+	}
+}
+
+func TestMigratorLockTimeout(t *testing.T) {
+	mu := &sync.Mutex{}
+	m := newSQLiteMigrator(testDB(t), testMigrations(), mu)
+
+	// Блокировку держит другой экземпляр: ожидание ограничено и заканчивается ошибкой, а не зависанием
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := m.withLock(ctx, func() error {
+		t.Fatal("fn выполнена без блокировки")
+		return nil
+	})
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("withLock вернул %v, ожидался ErrLockTimeout", err)
+	}
+}
+
+func TestDialectFor(t *testing.T) {
+	for driver, want := range map[string]goose.Dialect{
+		DriverPostgres: goose.DialectPostgres,
+		DriverMySQL:    goose.DialectMySQL,
+	} {
+		d, err := dialectFor(driver)
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		if d.gooseDialect() != want {
+			t.Fatalf("%s: диалект goose %q, ожидался %q", driver, d.gooseDialect(), want)
+		}
+	}
+
+	if _, err := dialectFor("oracle"); err == nil {
+		t.Fatal("неизвестный драйвер должен возвращать ошибку")
+	}
+	if got := (postgresDialect{}).placeholder(2); got != "$2" {
+		t.Fatalf("плейсхолдер Postgres %q", got)
+	}
+	if got := (mysqlDialect{}).placeholder(2); got != "?" {
+		t.Fatalf("плейсхолдер MySQL %q", got)
+	}
+}
+
+// testRecorder — Recorder, запоминающий наблюдения
+type testRecorder struct {
+	versions []int64
+	failed   int
+}
+
+func (r *testRecorder) ObserveMigration(version int64, _ time.Duration, err error) {
+	r.versions = append(r.versions, version)
+	if err != nil {
+		r.failed++
+	}
+}
@@ -0,0 +1,140 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/olezhek28/docker-compose-tutorial/migrations"
+)
+
+// testDBURIEnv — переменная со строкой подключения к Postgres для тестов с настоящей базой.
+// Без неё такие тесты пропускаются
+const testDBURIEnv = "TEST_DB_URI"
+
+// testPostgresDB — подключается к базе из TEST_DB_URI в отдельной пустой схеме, удаляемой по завершении теста
+func testPostgresDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	uri := os.Getenv(testDBURIEnv)
+	if uri == "" {
+		t.Skipf("%s не задана, тест с Postgres пропущен", testDBURIEnv)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg, err := pgx.ParseConfig(uri)
+	if err != nil {
+		t.Fatalf("разбор %s: %v", testDBURIEnv, err)
+	}
+	admin, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("подключение к %s: %v", testDBURIEnv, err)
+	}
+	defer admin.Close(ctx)
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("создание схемы: %v", err)
+	}
+	t.Cleanup(func() {
+		conn, err := pgx.ConnectConfig(context.Background(), cfg)
+		if err != nil {
+			t.Logf("схема %s не удалена: %v", schema, err)
+			return
+		}
+		defer conn.Close(context.Background())
+		if _, err := conn.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Logf("схема %s не удалена: %v", schema, err)
+		}
+	})
+
+	db := stdlib.OpenDB(*cfg, stdlib.OptionAfterConnect(func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, fmt.Sprintf("SET search_path TO %s, public", schema))
+		return err
+	}))
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func TestPostgresConcurrentUp(t *testing.T) {
+	db := testPostgresDB(t)
+	sources, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Экземпляры сервиса стартуют одновременно: advisory-блокировка пропускает к миграциям только одного
+	var wg sync.WaitGroup
+	total := make([]int, 2)
+	errs := make([]error, 2)
+	for i := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied, err := NewMigratorFS(db, migrations.FS).Apply()
+			total[i], errs[i] = len(applied), err
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if total[0]+total[1] != len(sources) {
+		t.Fatalf("применено миграций %v, ожидалось ровно %d на двоих", total, len(sources))
+	}
+	expectVersion(t, NewMigratorFS(db, migrations.FS), int64(len(sources)))
+}
+
+func TestPostgresFailedMigrationRollsBack(t *testing.T) {
+	db := testPostgresDB(t)
+	fsys := fstest.MapFS{
+		"001_create_accounts.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE accounts (id BIGSERIAL PRIMARY KEY, email TEXT NOT NULL);
+
+-- +goose Down
+DROP TABLE accounts;
+`)},
+		// CREATE INDEX CONCURRENTLY в транзакции невозможен: без аннотации миграция бы упала
+		"002_add_accounts_email_index.sql": {Data: []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY accounts_email_idx ON accounts (email);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY accounts_email_idx;
+`)},
+		"003_broken.sql": {Data: []byte(`-- +goose Up
+ALTER TABLE accounts ADD COLUMN name TEXT;
+ALTER TABLE missing_table ADD COLUMN name TEXT;
+
+-- +goose Down
+ALTER TABLE accounts DROP COLUMN name;
+`)},
+	}
+	m := NewMigratorFS(db, fsys)
+
+	if _, err := m.Apply(); err == nil {
+		t.Fatal("Apply с ошибочной миграцией завершился без ошибки")
+	}
+	// Упавшая миграция откатилась вместе с первой половиной, версия — последняя успешная
+	expectVersion(t, m, 2)
+	if _, err := db.Exec(`SELECT name FROM accounts`); err == nil {
+		t.Fatal("изменения упавшей миграции не откатились")
+	}
+}
@@ -0,0 +1,49 @@
+package migrator
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// Recorder — получатель метрик применения миграций. Мигратор не зависит от конкретной системы метрик:
+// реализацию, например для Prometheus, передают через SetRecorder
+type Recorder interface {
+	// ObserveMigration вызывается после каждой применённой миграции; err не nil, если миграция не удалась
+	ObserveMigration(version int64, duration time.Duration, err error)
+}
+
+// SetRecorder задаёт получателя метрик применения миграций; nil отключает запись метрик
+func (m *Migrator) SetRecorder(recorder Recorder) {
+	m.recorder = recorder
+}
+
+// recordUp передаёт получателю метрик результаты миграций, применённых goose, и логирует итог.
+// При сбое goose возвращает результаты не в results, а в *goose.PartialError: успешные миграции отдельно от упавшей
+func (m *Migrator) recordUp(results []*goose.MigrationResult, err error, elapsed time.Duration) {
+	var partialErr *goose.PartialError
+	if errors.As(err, &partialErr) {
+		results = append(results, partialErr.Applied...)
+		results = append(results, partialErr.Failed)
+	}
+
+	var applied, failed int
+	for _, result := range results {
+		if result.Error != nil {
+			failed++
+		} else {
+			applied++
+		}
+		if m.recorder != nil {
+			m.recorder.ObserveMigration(result.Source.Version, result.Duration, result.Error)
+		}
+	}
+
+	if failed > 0 || err != nil {
+		slog.Error("Применение миграций прервано", "applied", applied, "failed", failed, "duration", elapsed.String())
+		return
+	}
+	slog.Info("Применение миграций завершено", "applied", applied, "duration", elapsed.String())
+}
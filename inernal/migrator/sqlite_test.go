@@ -0,0 +1,108 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDialect — SQLite для тестов: второй диалект рядом с Postgres, на котором мигратор проверяется
+// без внешней базы. В SQLite нет межпроцессных блокировок уровня сессии, поэтому блокировка миграций —
+// мьютекс, общий для всех миграторов одной базы
+type sqliteDialect struct {
+	mu *sync.Mutex
+}
+
+var _ dialect = sqliteDialect{}
+
+func (sqliteDialect) gooseDialect() goose.Dialect {
+	return goose.DialectSQLite3
+}
+
+func (sqliteDialect) placeholder(int) string {
+	return "?"
+}
+
+func (sqliteDialect) tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	return count > 0, err
+}
+
+func (d sqliteDialect) tryLock(context.Context, *sql.Conn) (bool, error) {
+	return d.mu.TryLock(), nil
+}
+
+func (d sqliteDialect) unlock(context.Context, *sql.Conn) error {
+	d.mu.Unlock()
+	return nil
+}
+
+// testMigrations — переносимые миграции для проверки мигратора на SQLite: таблица, служебная таблица
+// контрольных сумм под тем же именем, что и в миграциях сервиса, изменение схемы и индекс без транзакции
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"001_create_accounts.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE accounts (
+    id    INTEGER PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE
+);
+
+-- +goose Down
+DROP TABLE accounts;
+`)},
+		"002_create_migration_checksums.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE migration_checksums (
+    version_id BIGINT PRIMARY KEY,
+    checksum   TEXT      NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE migration_checksums;
+`)},
+		"003_add_accounts_name.sql": {Data: []byte(`-- +goose Up
+ALTER TABLE accounts ADD COLUMN name TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE accounts DROP COLUMN name;
+`)},
+		"004_add_accounts_name_index.sql": {Data: []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX accounts_name_idx ON accounts (name);
+
+-- +goose Down
+DROP INDEX accounts_name_idx;
+`)},
+	}
+}
+
+// testDB — открывает чистую базу SQLite во временной директории теста
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	// Файл, а не :memory:: у каждого соединения базы в памяти была бы своя, пустая
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db")+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+// newSQLiteMigrator — мигратор с диалектом SQLite поверх db и миграций fsys; mu — общая блокировка базы
+func newSQLiteMigrator(db *sql.DB, fsys fstest.MapFS, mu *sync.Mutex) *Migrator {
+	return &Migrator{
+		db:            db,
+		migrationsDir: "тестовые миграции",
+		fsys:          fsys,
+		dialect:       sqliteDialect{mu: mu},
+	}
+}
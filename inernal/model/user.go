@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User — пользователь сервиса; используется и для парсинга JSON-запроса, и для формирования ответа
+type User struct {
+	ID        uuid.UUID  `json:"id"`                   // идентификатор пользователя
+	Username  string     `json:"username"`             // имя пользователя
+	Email     string     `json:"email"`                // email пользователя
+	CreatedAt time.Time  `json:"created_at"`           // время создания пользователя
+	UpdatedAt time.Time  `json:"updated_at"`           // время последнего изменения пользователя
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // время мягкого удаления; nil, если пользователь активен
+
+	// Password — пароль в открытом виде; приходит только во входящем JSON и очищается сразу после хеширования
+	Password string `json:"password,omitempty"`
+	// PasswordHash — bcrypt-хеш пароля; никогда не попадает в JSON-ответ
+	PasswordHash string `json:"-"`
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sony/gobreaker/v2"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+// ErrUnavailable возвращается без обращения к базе, пока автоматический выключатель разомкнут
+var ErrUnavailable = errors.New("база данных временно недоступна")
+
+// BreakerOptions — настройки автоматического выключателя
+type BreakerOptions struct {
+	// Failures — число ошибок подряд, после которого выключатель размыкается
+	Failures uint32
+	// Cooldown — сколько выключатель остаётся разомкнутым, прежде чем пропустить пробный запрос
+	Cooldown time.Duration
+	// OnStateChange — необязательный обработчик смены состояния, например для метрик
+	OnStateChange func(state gobreaker.State)
+}
+
+// BreakerUserRepository — UserRepository с автоматическим выключателем: после серии ошибок базы
+// запросы сразу получают ErrUnavailable, а не ждут таймаута, занимая соединения пула.
+// По истечении Cooldown пропускается один пробный запрос; если он успешен, выключатель замыкается
+type BreakerUserRepository struct {
+	next    UserRepository
+	breaker *gobreaker.CircuitBreaker[struct{}]
+}
+
+func NewBreakerUserRepository(next UserRepository, opts BreakerOptions) *BreakerUserRepository {
+	settings := gobreaker.Settings{
+		Name:        "database",
+		MaxRequests: 1,
+		Timeout:     opts.Cooldown,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= opts.Failures
+		},
+		IsSuccessful: isBreakerSuccess,
+		OnStateChange: func(_ string, from, to gobreaker.State) {
+			slog.Warn("Автоматический выключатель базы данных сменил состояние", "from", from.String(), "to", to.String())
+			if opts.OnStateChange != nil {
+				opts.OnStateChange(to)
+			}
+		},
+	}
+
+	return &BreakerUserRepository{
+		next:    next,
+		breaker: gobreaker.NewCircuitBreaker[struct{}](settings),
+	}
+}
+
+// Unwrap возвращает обёрнутое хранилище
+func (r *BreakerUserRepository) Unwrap() UserRepository {
+	return r.next
+}
+
+// CircuitState возвращает состояние выключателя: closed, half-open или open
+func (r *BreakerUserRepository) CircuitState() string {
+	return r.breaker.State().String()
+}
+
+func (r *BreakerUserRepository) Create(ctx context.Context, user *model.User) error {
+	return r.execute(func() error { return r.next.Create(ctx, user) })
+}
+
+func (r *BreakerUserRepository) CreateBatch(ctx context.Context, users []model.User) error {
+	return r.execute(func() error { return r.next.CreateBatch(ctx, users) })
+}
+
+func (r *BreakerUserRepository) GetByID(ctx context.Context, id uuid.UUID) (model.User, error) {
+	var user model.User
+	err := r.execute(func() (err error) {
+		user, err = r.next.GetByID(ctx, id)
+		return err
+	})
+
+	return user, err
+}
+
+func (r *BreakerUserRepository) List(ctx context.Context, params ListParams) ([]model.User, error) {
+	var users []model.User
+	err := r.execute(func() (err error) {
+		users, err = r.next.List(ctx, params)
+		return err
+	})
+
+	return users, err
+}
+
+func (r *BreakerUserRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	var count int64
+	err := r.execute(func() (err error) {
+		count, err = r.next.Count(ctx, filter)
+		return err
+	})
+
+	return count, err
+}
+
+func (r *BreakerUserRepository) Update(ctx context.Context, user *model.User) error {
+	return r.execute(func() error { return r.next.Update(ctx, user) })
+}
+
+func (r *BreakerUserRepository) Patch(ctx context.Context, id uuid.UUID, patch UserPatch) (model.User, error) {
+	var user model.User
+	err := r.execute(func() (err error) {
+		user, err = r.next.Patch(ctx, id, patch)
+		return err
+	})
+
+	return user, err
+}
+
+func (r *BreakerUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.execute(func() error { return r.next.Delete(ctx, id) })
+}
+
+func (r *BreakerUserRepository) Restore(ctx context.Context, id uuid.UUID) (model.User, error) {
+	var user model.User
+	err := r.execute(func() (err error) {
+		user, err = r.next.Restore(ctx, id)
+		return err
+	})
+
+	return user, err
+}
+
+func (r *BreakerUserRepository) DeleteMatching(ctx context.Context, filter ListFilter) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.execute(func() (err error) {
+		ids, err = r.next.DeleteMatching(ctx, filter)
+		return err
+	})
+
+	return ids, err
+}
+
+// execute выполняет fn через выключатель и заменяет его собственные отказы на ErrUnavailable
+func (r *BreakerUserRepository) execute(fn func() error) error {
+	_, err := r.breaker.Execute(func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return ErrUnavailable
+	}
+
+	return err
+}
+
+// isBreakerSuccess — ошибки предметной области и отмена запроса клиентом говорят о том, что база
+// отвечает, поэтому не считаются сбоями
+func isBreakerSuccess(err error) bool {
+	return err == nil ||
+		errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrEmailTaken) ||
+		errors.Is(err, ErrUsernameTaken) ||
+		errors.Is(err, ErrNotDeleted) ||
+		errors.Is(err, context.Canceled)
+}
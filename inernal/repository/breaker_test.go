@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sony/gobreaker/v2"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+// flakyUserRepository — хранилище в памяти, чей GetByID возвращает err, пока она задана, и считает вызовы
+type flakyUserRepository struct {
+	*MemoryUserRepository
+	err   error
+	calls int
+}
+
+func (r *flakyUserRepository) GetByID(ctx context.Context, id uuid.UUID) (model.User, error) {
+	r.calls++
+	if r.err != nil {
+		return model.User{}, r.err
+	}
+
+	return r.MemoryUserRepository.GetByID(ctx, id)
+}
+
+// testBreaker — выключатель поверх flaky, размыкающийся после двух ошибок подряд; states собирает смены состояния
+func testBreaker(flaky *flakyUserRepository, states *[]gobreaker.State) *BreakerUserRepository {
+	return NewBreakerUserRepository(flaky, BreakerOptions{
+		Failures: 2,
+		Cooldown: 20 * time.Millisecond,
+		OnStateChange: func(state gobreaker.State) {
+			*states = append(*states, state)
+		},
+	})
+}
+
+func TestBreakerOpensAndCloses(t *testing.T) {
+	ctx := context.Background()
+	flaky := &flakyUserRepository{MemoryUserRepository: NewMemoryUserRepository(), err: errors.New("соединение сброшено")}
+	var states []gobreaker.State
+	repo := testBreaker(flaky, &states)
+	user := createTestUser(t, repo, "alice", "alice@example.com")
+
+	// Две ошибки базы подряд размыкают выключатель
+	for range 2 {
+		if _, err := repo.GetByID(ctx, user.ID); err == nil || errors.Is(err, ErrUnavailable) {
+			t.Fatalf("ошибка базы вернулась как %v", err)
+		}
+	}
+	if repo.CircuitState() != "open" {
+		t.Fatalf("после двух ошибок состояние %s", repo.CircuitState())
+	}
+
+	// Разомкнутый выключатель отказывает сразу, не обращаясь к базе
+	if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("GetByID при разомкнутом выключателе вернул %v, ожидался ErrUnavailable", err)
+	}
+	if flaky.calls != 2 {
+		t.Fatalf("к базе ушло %d запросов, ожидалось 2", flaky.calls)
+	}
+
+	// После паузы пробный запрос снова падает: выключатель размыкается заново
+	time.Sleep(30 * time.Millisecond)
+	if repo.CircuitState() != "half-open" {
+		t.Fatalf("после паузы состояние %s, ожидалось half-open", repo.CircuitState())
+	}
+	if _, err := repo.GetByID(ctx, user.ID); err == nil || errors.Is(err, ErrUnavailable) {
+		t.Fatalf("пробный запрос вернул %v", err)
+	}
+	if repo.CircuitState() != "open" {
+		t.Fatalf("после неудачной пробы состояние %s", repo.CircuitState())
+	}
+
+	// База поднялась: успешная проба замыкает выключатель
+	flaky.err = nil
+	time.Sleep(30 * time.Millisecond)
+	if _, err := repo.GetByID(ctx, user.ID); err != nil {
+		t.Fatalf("пробный запрос после восстановления: %v", err)
+	}
+	if repo.CircuitState() != "closed" {
+		t.Fatalf("после успешной пробы состояние %s", repo.CircuitState())
+	}
+
+	want := []gobreaker.State{gobreaker.StateOpen, gobreaker.StateHalfOpen, gobreaker.StateOpen,
+		gobreaker.StateHalfOpen, gobreaker.StateClosed}
+	if !slices.Equal(states, want) {
+		t.Fatalf("смены состояния %v, ожидались %v", states, want)
+	}
+}
+
+func TestBreakerIgnoresDomainErrors(t *testing.T) {
+	ctx := context.Background()
+	flaky := &flakyUserRepository{MemoryUserRepository: NewMemoryUserRepository()}
+	var states []gobreaker.State
+	repo := testBreaker(flaky, &states)
+
+	// Отсутствующий пользователь и отмена клиентом не говорят о сбое базы
+	for _, err := range []error{ErrNotFound, context.Canceled, ErrNotFound} {
+		flaky.err = err
+		if _, got := repo.GetByID(ctx, uuid.New()); !errors.Is(got, err) {
+			t.Fatalf("GetByID вернул %v, ожидалась %v", got, err)
+		}
+	}
+	if repo.CircuitState() != "closed" || len(states) != 0 {
+		t.Fatalf("ошибки предметной области разомкнули выключатель: %s, %v", repo.CircuitState(), states)
+	}
+}
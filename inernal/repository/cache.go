@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+// CacheOptions — настройки кэша пользователей в памяти
+type CacheOptions struct {
+	// Size — максимальное число пользователей в кэше; при переполнении вытесняется давно не читавшийся
+	Size int
+	// TTL — сколько запись остаётся актуальной после чтения из базы
+	TTL time.Duration
+}
+
+// UserCache — кэш пользователей по идентификатору. Ошибки кэша не должны ломать запросы,
+// поэтому методы их не возвращают: недоступный кэш ведёт себя как пустой
+type UserCache interface {
+	// Get возвращает пользователя из кэша; false означает промах
+	Get(ctx context.Context, id uuid.UUID) (model.User, bool)
+	// Put сохраняет пользователя в кэш
+	Put(ctx context.Context, user model.User)
+	// Remove сбрасывает запись пользователя
+	Remove(ctx context.Context, id uuid.UUID)
+}
+
+// CachedUserRepository — UserRepository с кэшем для GetByID. Запись сбрасывается при изменении
+// или удалении пользователя. С кэшем в памяти процесса изменения из других экземпляров сервиса
+// становятся видны не позже чем через TTL; общий кэш вроде Redis сбрасывается для всех сразу
+type CachedUserRepository struct {
+	UserRepository
+	cache UserCache
+}
+
+func NewCachedUserRepository(next UserRepository, cache UserCache) *CachedUserRepository {
+	return &CachedUserRepository{
+		UserRepository: next,
+		cache:          cache,
+	}
+}
+
+// Unwrap возвращает обёрнутое хранилище
+func (r *CachedUserRepository) Unwrap() UserRepository {
+	return r.UserRepository
+}
+
+func (r *CachedUserRepository) GetByID(ctx context.Context, id uuid.UUID) (model.User, error) {
+	if user, ok := r.cache.Get(ctx, id); ok {
+		return user, nil
+	}
+
+	user, err := r.UserRepository.GetByID(ctx, id)
+	if err != nil {
+		return model.User{}, err
+	}
+	r.cache.Put(ctx, user)
+
+	return user, nil
+}
+
+func (r *CachedUserRepository) Update(ctx context.Context, user *model.User) error {
+	// Сбрасываем запись и при ошибке: неизвестно, успела ли база применить изменение
+	defer r.cache.Remove(ctx, user.ID)
+	return r.UserRepository.Update(ctx, user)
+}
+
+func (r *CachedUserRepository) Patch(ctx context.Context, id uuid.UUID, patch UserPatch) (model.User, error) {
+	defer r.cache.Remove(ctx, id)
+	return r.UserRepository.Patch(ctx, id, patch)
+}
+
+func (r *CachedUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer r.cache.Remove(ctx, id)
+	return r.UserRepository.Delete(ctx, id)
+}
+
+func (r *CachedUserRepository) Restore(ctx context.Context, id uuid.UUID) (model.User, error) {
+	// Удалённый пользователь в кэш не попадает, но сбрасываем запись на случай гонки с удалением
+	defer r.cache.Remove(ctx, id)
+	return r.UserRepository.Restore(ctx, id)
+}
+
+func (r *CachedUserRepository) DeleteMatching(ctx context.Context, filter ListFilter) ([]uuid.UUID, error) {
+	ids, err := r.UserRepository.DeleteMatching(ctx, filter)
+	// Какие записи затронуты, известно только при успехе; при ошибке транзакция откачена и кэш верен
+	for _, id := range ids {
+		r.cache.Remove(ctx, id)
+	}
+
+	return ids, err
+}
+
+// cacheEntry — пользователь в кэше и момент, после которого запись устаревает
+type cacheEntry struct {
+	user      model.User
+	expiresAt time.Time
+}
+
+// LRUCache — потокобезопасный LRU-кэш пользователей в памяти процесса с ограничением времени жизни записей.
+// Список упорядочен от недавно прочитанных к давно не читавшимся
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[uuid.UUID]*list.Element
+}
+
+func NewLRUCache(opts CacheOptions) *LRUCache {
+	return &LRUCache{
+		size:    opts.Size,
+		ttl:     opts.TTL,
+		order:   list.New(),
+		entries: make(map[uuid.UUID]*list.Element, opts.Size),
+	}
+}
+
+func (c *LRUCache) Get(_ context.Context, id uuid.UUID) (model.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[id]
+	if !ok {
+		return model.User{}, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, id)
+		return model.User{}, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.user, true
+}
+
+func (c *LRUCache) Put(_ context.Context, user model.User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{user: user, expiresAt: time.Now().Add(c.ttl)}
+	if element, ok := c.entries[user.ID]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[user.ID] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).user.ID)
+	}
+}
+
+func (c *LRUCache) Remove(_ context.Context, id uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[id]; ok {
+		c.order.Remove(element)
+		delete(c.entries, id)
+	}
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+func TestCachedRepositoryServesFromCache(t *testing.T) {
+	ctx := context.Background()
+	// flaky без ошибки — обычное хранилище в памяти, считающее обращения GetByID
+	db := &flakyUserRepository{MemoryUserRepository: NewMemoryUserRepository()}
+	users := NewCachedUserRepository(db, NewLRUCache(CacheOptions{Size: 10, TTL: time.Minute}))
+	user := createTestUser(t, users, "alice", "alice@example.com")
+
+	// Второе чтение обслуживается кэшем, без обращения к базе
+	for range 2 {
+		if _, err := users.GetByID(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if db.calls != 1 {
+		t.Fatalf("к базе ушло %d запросов, ожидался один", db.calls)
+	}
+
+	// Обновление сбрасывает запись: следующее чтение идёт в базу и видит новые данные
+	user.Email = "alice@example.org"
+	if err := users.Update(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	got, err := users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.calls != 2 || got.Email != "alice@example.org" {
+		t.Fatalf("после обновления: запросов к базе %d, email %q", db.calls, got.Email)
+	}
+
+	// Частичное обновление и удаление тоже сбрасывают запись
+	username := "alice2"
+	if _, err := users.Patch(ctx, user.ID, UserPatch{Username: &username}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.GetByID(ctx, user.ID); err != nil || got.Username != username {
+		t.Fatalf("после Patch прочитан %+v, %v", got, err)
+	}
+	if err := users.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.GetByID(ctx, user.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("после удаления GetByID вернул %v, ожидался ErrNotFound", err)
+	}
+}
+
+func TestLRUCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(CacheOptions{Size: 2, TTL: time.Minute})
+	first, second, third := model.User{ID: uuid.New()}, model.User{ID: uuid.New()}, model.User{ID: uuid.New()}
+
+	cache.Put(ctx, first)
+	cache.Put(ctx, second)
+	// Чтение делает first недавно использованным, поэтому при переполнении вытесняется second
+	if _, ok := cache.Get(ctx, first.ID); !ok {
+		t.Fatal("промах сразу после записи")
+	}
+	cache.Put(ctx, third)
+
+	if _, ok := cache.Get(ctx, second.ID); ok {
+		t.Fatal("давно не читавшаяся запись не вытеснена")
+	}
+	for _, user := range []model.User{first, third} {
+		if _, ok := cache.Get(ctx, user.ID); !ok {
+			t.Fatalf("запись %s вытеснена раньше времени", user.ID)
+		}
+	}
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(CacheOptions{Size: 10, TTL: 10 * time.Millisecond})
+	user := model.User{ID: uuid.New()}
+
+	cache.Put(ctx, user)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := cache.Get(ctx, user.ID); ok {
+		t.Fatal("запись не устарела по TTL")
+	}
+}
+
+func TestLRUCacheConcurrent(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(CacheOptions{Size: 8, TTL: time.Minute})
+	ids := make([]uuid.UUID, 16)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+
+	// Под -race проверяет, что параллельные чтения, записи и сбросы не гоняются за общим состоянием
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				id := ids[(worker+i)%len(ids)]
+				cache.Put(ctx, model.User{ID: id})
+				cache.Get(ctx, id)
+				if i%5 == 0 {
+					cache.Remove(ctx, id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cache.order.Len() > 8 || len(cache.entries) != cache.order.Len() {
+		t.Fatalf("в кэше %d записей в списке и %d в индексе, лимит 8", cache.order.Len(), len(cache.entries))
+	}
+}
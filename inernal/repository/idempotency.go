@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IdempotencyRecord — сохранённый ответ на запрос с ключом идемпотентности
+type IdempotencyRecord struct {
+	Key string
+	// RequestHash — хеш тела исходного запроса; по нему отличаем повтор от другого запроса с тем же ключом
+	RequestHash string
+	// StatusCode — статус сохранённого ответа; ноль означает, что запрос с этим ключом ещё выполняется
+	StatusCode int
+	Headers    map[string]string
+	Body       []byte
+	ExpiresAt  time.Time
+}
+
+// Pending сообщает, что ключ забронирован, но ответ на запрос ещё не сохранён
+func (r IdempotencyRecord) Pending() bool {
+	return r.StatusCode == 0
+}
+
+// IdempotencyStore — хранилище ключей идемпотентности. Ключ сначала бронируется, и только обладатель брони
+// выполняет запрос: так два параллельных запроса с одним ключом не выполнятся оба
+type IdempotencyStore interface {
+	// Reserve атомарно бронирует ключ record.Key до record.ExpiresAt и возвращает true.
+	// Если у ключа уже есть действующая запись — ответ или чужая бронь, — возвращает её и false
+	Reserve(ctx context.Context, record IdempotencyRecord) (IdempotencyRecord, bool, error)
+	// Complete сохраняет ответ в забронированную запись и продлевает её до record.ExpiresAt
+	Complete(ctx context.Context, record IdempotencyRecord) error
+	// Release снимает бронь без ответа, чтобы запрос с этим ключом можно было повторить
+	Release(ctx context.Context, key string) error
+}
+
+// reserveAttempts — сколько раз пытаемся забронировать ключ, если чужая бронь исчезает между вставкой и чтением
+const reserveAttempts = 3
+
+// PostgresIdempotencyStore — реализация IdempotencyStore поверх таблицы idempotency_keys
+type PostgresIdempotencyStore struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresIdempotencyStore(db *pgxpool.Pool) *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{
+		db: db,
+	}
+}
+
+func (s *PostgresIdempotencyStore) Reserve(ctx context.Context, record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	// Заодно удаляем просроченные ключи, чтобы таблица не росла бесконечно и просроченный ключ можно было занять
+	if _, err := s.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP`); err != nil {
+		return IdempotencyRecord{}, false, fmt.Errorf("ошибка очистки просроченных ключей идемпотентности: %w", err)
+	}
+
+	// Первичный ключ гарантирует, что бронь получит только один из параллельных запросов
+	insert := `INSERT INTO idempotency_keys (key, request_hash, expires_at)
+		VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING`
+	query := `SELECT key, request_hash, COALESCE(status_code, 0), headers, COALESCE(body, ''::bytea), expires_at
+		FROM idempotency_keys WHERE key = $1 AND expires_at > CURRENT_TIMESTAMP`
+
+	for range reserveAttempts {
+		tag, err := s.db.Exec(ctx, insert, record.Key, record.RequestHash, record.ExpiresAt)
+		if err != nil {
+			return IdempotencyRecord{}, false, fmt.Errorf("ошибка бронирования ключа идемпотентности: %w", err)
+		}
+		if tag.RowsAffected() == 1 {
+			return record, true, nil
+		}
+
+		var existing IdempotencyRecord
+		err = s.db.QueryRow(ctx, query, record.Key).Scan(
+			&existing.Key, &existing.RequestHash, &existing.StatusCode, &existing.Headers, &existing.Body, &existing.ExpiresAt,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Чужую бронь сняли или она истекла после нашей вставки — пробуем занять ключ снова
+			continue
+		}
+		if err != nil {
+			return IdempotencyRecord{}, false, fmt.Errorf("ошибка чтения ключа идемпотентности: %w", err)
+		}
+
+		return existing, false, nil
+	}
+
+	return IdempotencyRecord{}, false, fmt.Errorf("не удалось забронировать ключ идемпотентности за %d попытки", reserveAttempts)
+}
+
+func (s *PostgresIdempotencyStore) Complete(ctx context.Context, record IdempotencyRecord) error {
+	// Обновляем только свою незавершённую бронь: если она истекла и ключ занял другой запрос, его запись не трогаем
+	query := `UPDATE idempotency_keys SET status_code = $3, headers = $4, body = $5, expires_at = $6
+		WHERE key = $1 AND request_hash = $2 AND status_code IS NULL`
+	tag, err := s.db.Exec(ctx, query,
+		record.Key, record.RequestHash, record.StatusCode, record.Headers, record.Body, record.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения ключа идемпотентности: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("бронь ключа идемпотентности истекла до сохранения ответа")
+	}
+
+	return nil
+}
+
+func (s *PostgresIdempotencyStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND status_code IS NULL`, key)
+	if err != nil {
+		return fmt.Errorf("ошибка снятия брони ключа идемпотентности: %w", err)
+	}
+
+	return nil
+}
+
+// MemoryIdempotencyStore — потокобезопасная реализация IdempotencyStore в памяти процесса
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]IdempotencyRecord),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(_ context.Context, record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, existing := range s.records {
+		if !existing.ExpiresAt.After(now) {
+			delete(s.records, key)
+		}
+	}
+
+	if existing, ok := s.records[record.Key]; ok {
+		return existing, false, nil
+	}
+
+	record.StatusCode = 0
+	record.Headers = nil
+	record.Body = nil
+	s.records[record.Key] = record
+
+	return record, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Как и в Postgres, сохраняем ответ только в свою незавершённую бронь
+	existing, ok := s.records[record.Key]
+	if !ok || !existing.Pending() || existing.RequestHash != record.RequestHash {
+		return errors.New("бронь ключа идемпотентности истекла до сохранения ответа")
+	}
+	s.records[record.Key] = record
+
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[key]; ok && existing.Pending() {
+		delete(s.records, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	testIdempotencyStore(t, NewMemoryIdempotencyStore())
+}
+
+func TestPostgresIdempotencyStore(t *testing.T) {
+	testIdempotencyStore(t, NewPostgresIdempotencyStore(testPool(t)))
+}
+
+// testIdempotencyStore — общие проверки поведения для всех реализаций IdempotencyStore
+func testIdempotencyStore(t *testing.T, store IdempotencyStore) {
+	ctx := context.Background()
+	pending := func(key string) IdempotencyRecord {
+		return IdempotencyRecord{Key: key, RequestHash: "hash-" + key, ExpiresAt: time.Now().Add(time.Minute)}
+	}
+
+	t.Run("бронь и ответ", func(t *testing.T) {
+		if _, reserved, err := store.Reserve(ctx, pending("complete")); err != nil || !reserved {
+			t.Fatalf("первая бронь: reserved=%v, err=%v", reserved, err)
+		}
+
+		existing, reserved, err := store.Reserve(ctx, pending("complete"))
+		if err != nil || reserved {
+			t.Fatalf("повторная бронь: reserved=%v, err=%v", reserved, err)
+		}
+		if !existing.Pending() {
+			t.Fatalf("до ответа запись должна быть незавершённой: %+v", existing)
+		}
+
+		err = store.Complete(ctx, IdempotencyRecord{
+			Key:         "complete",
+			RequestHash: "hash-complete",
+			StatusCode:  201,
+			Headers:     map[string]string{"Content-Type": "application/json"},
+			Body:        []byte(`{"data":{}}`),
+			ExpiresAt:   time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		existing, reserved, err = store.Reserve(ctx, pending("complete"))
+		if err != nil || reserved {
+			t.Fatalf("бронь после ответа: reserved=%v, err=%v", reserved, err)
+		}
+		if existing.StatusCode != 201 || string(existing.Body) != `{"data":{}}` ||
+			existing.Headers["Content-Type"] != "application/json" {
+			t.Fatalf("сохранён не тот ответ: %+v", existing)
+		}
+
+		// Завершённую запись снять бронью нельзя
+		if err := store.Release(ctx, "complete"); err != nil {
+			t.Fatal(err)
+		}
+		if _, reserved, _ := store.Reserve(ctx, pending("complete")); reserved {
+			t.Fatal("Release удалил сохранённый ответ")
+		}
+	})
+
+	t.Run("снятие брони", func(t *testing.T) {
+		if _, reserved, err := store.Reserve(ctx, pending("release")); err != nil || !reserved {
+			t.Fatalf("бронь: reserved=%v, err=%v", reserved, err)
+		}
+		if err := store.Release(ctx, "release"); err != nil {
+			t.Fatal(err)
+		}
+		if _, reserved, err := store.Reserve(ctx, pending("release")); err != nil || !reserved {
+			t.Fatalf("после снятия ключ должен быть свободен: reserved=%v, err=%v", reserved, err)
+		}
+	})
+
+	t.Run("просроченная бронь", func(t *testing.T) {
+		expired := pending("expired")
+		expired.ExpiresAt = time.Now().Add(-time.Second)
+		if _, reserved, err := store.Reserve(ctx, expired); err != nil || !reserved {
+			t.Fatalf("бронь: reserved=%v, err=%v", reserved, err)
+		}
+		if _, reserved, err := store.Reserve(ctx, pending("expired")); err != nil || !reserved {
+			t.Fatalf("просроченный ключ должен заниматься заново: reserved=%v, err=%v", reserved, err)
+		}
+	})
+
+	t.Run("ответ без брони", func(t *testing.T) {
+		err := store.Complete(ctx, IdempotencyRecord{
+			Key: "missing", RequestHash: "hash-missing", StatusCode: 201, ExpiresAt: time.Now().Add(time.Hour),
+		})
+		if err == nil {
+			t.Fatal("ответ без брони не должен сохраняться")
+		}
+	})
+
+	t.Run("параллельная бронь", func(t *testing.T) {
+		const workers = 10
+
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			reserved int
+		)
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, ok, err := store.Reserve(ctx, pending("race"))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if ok {
+					mu.Lock()
+					reserved++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if reserved != 1 {
+			t.Fatalf("ключ забронировали %d раз, ожидался ровно один", reserved)
+		}
+	})
+}
@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+// MemoryUserRepository — потокобезопасная реализация UserRepository в памяти.
+// Повторяет поведение Postgres-реализации, включая уникальность email, и нужна для быстрых тестов обработчиков
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[uuid.UUID]model.User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users: make(map[uuid.UUID]model.User),
+	}
+}
+
+func (r *MemoryUserRepository) Create(_ context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.conflict(*user, uuid.Nil); err != nil {
+		return err
+	}
+
+	user.ID = uuid.New()
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	r.users[user.ID] = *user
+
+	return nil
+}
+
+func (r *MemoryUserRepository) CreateBatch(_ context.Context, users []model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Сначала проверяем весь пакет, чтобы при ошибке ничего не сохранить,
+	// включая повторы email и имён внутри самого пакета
+	seenEmails := make(map[string]struct{}, len(users))
+	seenUsernames := make(map[string]struct{}, len(users))
+	for _, user := range users {
+		if _, ok := seenEmails[user.Email]; ok {
+			return ErrEmailTaken
+		}
+		if _, ok := seenUsernames[user.Username]; ok {
+			return ErrUsernameTaken
+		}
+		if err := r.conflict(user, uuid.Nil); err != nil {
+			return err
+		}
+		seenEmails[user.Email] = struct{}{}
+		seenUsernames[user.Username] = struct{}{}
+	}
+
+	now := time.Now()
+	for i := range users {
+		users[i].ID = uuid.New()
+		users[i].CreatedAt = now
+		users[i].UpdatedAt = now
+		r.users[users[i].ID] = users[i]
+	}
+
+	return nil
+}
+
+func (r *MemoryUserRepository) GetByID(_ context.Context, id uuid.UUID) (model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return model.User{}, ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (r *MemoryUserRepository) List(_ context.Context, params ListParams) ([]model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := r.filter(params.ListFilter)
+	compare := func(a, b model.User) int {
+		c := a.CreatedAt.Compare(b.CreatedAt)
+		if params.Sort.Field == SortByUsername {
+			c = strings.Compare(a.Username, b.Username)
+		}
+		if c == 0 {
+			c = strings.Compare(a.ID.String(), b.ID.String())
+		}
+		if params.Sort.Desc {
+			return -c
+		}
+		return c
+	}
+	slices.SortFunc(users, compare)
+
+	// Keyset-пагинация: отбрасываем всё, что не идёт строго после курсора
+	if params.After != nil {
+		after := model.User{CreatedAt: params.After.CreatedAt, Username: params.After.Username, ID: params.After.ID}
+		start, _ := slices.BinarySearchFunc(users, after, compare)
+		if start < len(users) && compare(users[start], after) == 0 {
+			start++
+		}
+		users = users[start:]
+	}
+
+	return paginate(users, params), nil
+}
+
+func (r *MemoryUserRepository) Count(_ context.Context, filter ListFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.filter(filter))), nil
+}
+
+func (r *MemoryUserRepository) Update(_ context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[user.ID]
+	if !ok || existing.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if err := r.conflict(*user, user.ID); err != nil {
+		return err
+	}
+
+	existing.Username = user.Username
+	existing.Email = user.Email
+	if user.PasswordHash != "" {
+		existing.PasswordHash = user.PasswordHash
+	}
+	existing.UpdatedAt = time.Now()
+	r.users[user.ID] = existing
+	*user = existing
+
+	return nil
+}
+
+func (r *MemoryUserRepository) Patch(_ context.Context, id uuid.UUID, patch UserPatch) (model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return model.User{}, ErrNotFound
+	}
+
+	if patch.Username != nil {
+		user.Username = *patch.Username
+	}
+	if patch.Email != nil {
+		user.Email = *patch.Email
+	}
+	if err := r.conflict(user, id); err != nil {
+		return model.User{}, err
+	}
+
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+
+	return user, nil
+}
+
+func (r *MemoryUserRepository) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	now := time.Now()
+	user.DeletedAt = &now
+	user.UpdatedAt = now
+	r.users[id] = user
+
+	return nil
+}
+
+func (r *MemoryUserRepository) Restore(_ context.Context, id uuid.UUID) (model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return model.User{}, ErrNotFound
+	}
+	if user.DeletedAt == nil {
+		return model.User{}, ErrNotDeleted
+	}
+	if err := r.conflict(user, id); err != nil {
+		return model.User{}, err
+	}
+
+	user.DeletedAt = nil
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+
+	return user, nil
+}
+
+func (r *MemoryUserRepository) DeleteMatching(_ context.Context, filter ListFilter) ([]uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	filter.IncludeDeleted = false
+	now := time.Now()
+
+	ids := make([]uuid.UUID, 0)
+	for _, user := range r.filter(filter) {
+		user.DeletedAt = &now
+		user.UpdatedAt = now
+		r.users[user.ID] = user
+		ids = append(ids, user.ID)
+	}
+
+	return ids, nil
+}
+
+// filter возвращает пользователей, подходящих под фильтр, в произвольном порядке
+func (r *MemoryUserRepository) filter(filter ListFilter) []model.User {
+	users := make([]model.User, 0, len(r.users))
+	for _, user := range r.users {
+		if user.DeletedAt != nil && !filter.IncludeDeleted {
+			continue
+		}
+		if filter.UsernameQuery != "" &&
+			!strings.Contains(strings.ToLower(user.Username), strings.ToLower(filter.UsernameQuery)) {
+			continue
+		}
+		if filter.Email != "" && user.Email != filter.Email {
+			continue
+		}
+		users = append(users, user)
+	}
+
+	return users
+}
+
+// conflict проверяет, заняты ли email или имя пользователя другим активным пользователем, кроме exceptID,
+// и возвращает ту же ошибку, что и уникальные индексы в Postgres
+func (r *MemoryUserRepository) conflict(candidate model.User, exceptID uuid.UUID) error {
+	for _, user := range r.users {
+		if user.ID == exceptID || user.DeletedAt != nil {
+			continue
+		}
+		if user.Email == candidate.Email {
+			return ErrEmailTaken
+		}
+		if user.Username == candidate.Username {
+			return ErrUsernameTaken
+		}
+	}
+
+	return nil
+}
+
+// paginate вырезает из отсортированного среза страницу согласно limit и offset
+func paginate(users []model.User, params ListParams) []model.User {
+	if params.Offset >= len(users) {
+		return make([]model.User, 0)
+	}
+
+	end := min(params.Offset+params.Limit, len(users))
+
+	return users[params.Offset:end]
+}
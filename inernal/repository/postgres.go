@@ -0,0 +1,450 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+// userColumns — колонки пользователя в порядке, который ожидает scanUser
+const userColumns = `id, username, email, created_at, updated_at, deleted_at`
+
+// uniqueViolationCode — код ошибки Postgres (SQLSTATE) при нарушении ограничения уникальности
+const uniqueViolationCode = "23505"
+
+// Имена уникальных индексов из миграций; по ним определяем, какое поле вызвало конфликт
+const (
+	emailUniqueConstraint    = "users_email_key"
+	usernameUniqueConstraint = "users_username_key"
+)
+
+// PostgresUserRepository — реализация UserRepository поверх пула соединений pgx
+type PostgresUserRepository struct {
+	db    *pgxpool.Pool
+	retry RetryPolicy
+}
+
+func NewPostgresUserRepository(db *pgxpool.Pool, retry RetryPolicy) *PostgresUserRepository {
+	return &PostgresUserRepository{
+		db:    db,
+		retry: retry,
+	}
+}
+
+func (r *PostgresUserRepository) Create(ctx context.Context, user *model.User) error {
+	// Вставка выполняется в транзакции, чтобы связанные записи (например, аудит) можно было
+	// добавить сюда же и сохранить атомарность. При временной ошибке транзакция повторяется целиком
+	return r.retry.do(ctx, "create_user", func() error {
+		return r.InTx(ctx, func(tx pgx.Tx) error {
+			// SQL-запрос на вставку данных в таблицу users, возвращающий сгенерированные поля
+			query := `INSERT INTO users (username, email, password_hash) VALUES ($1, $2, $3) RETURNING ` + userColumns
+			err := scanUser(tx.QueryRow(ctx, query, user.Username, user.Email, user.PasswordHash), user)
+			if err != nil {
+				return translateError(err)
+			}
+
+			return nil
+		})
+	})
+}
+
+// copyBatchThreshold — с какого размера пакет загружается через COPY. На маленьких пакетах
+// дополнительный запрос за сгенерированными полями съедает выигрыш от COPY
+const copyBatchThreshold = 100
+
+// CreateBatch сохраняет пакет целиком в одной транзакции: ошибка на любом элементе откатывает весь пакет.
+// Большие пакеты загружаются через COPY, маленькие — пакетом INSERT ... RETURNING
+func (r *PostgresUserRepository) CreateBatch(ctx context.Context, users []model.User) error {
+	return r.InTx(ctx, func(tx pgx.Tx) error {
+		if len(users) >= copyBatchThreshold {
+			return copyUsers(ctx, tx, users)
+		}
+
+		return insertUsers(ctx, tx, users)
+	})
+}
+
+// insertUsers — вставляет пользователей по одному INSERT ... RETURNING, отправляя их одним пакетом
+func insertUsers(ctx context.Context, tx pgx.Tx, users []model.User) error {
+	query := `INSERT INTO users (username, email, password_hash) VALUES ($1, $2, $3) RETURNING ` + userColumns
+
+	// Отправляем вставки одним пакетом, чтобы не платить за сетевой round-trip на каждую строку
+	batch := &pgx.Batch{}
+	for i := range users {
+		batch.Queue(query, users[i].Username, users[i].Email, users[i].PasswordHash)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	for i := range users {
+		if err := scanUser(results.QueryRow(), &users[i]); err != nil {
+			_ = results.Close()
+			return translateError(err)
+		}
+	}
+
+	if err := results.Close(); err != nil {
+		return fmt.Errorf("ошибка пакетной вставки пользователей: %w", err)
+	}
+
+	return nil
+}
+
+// copyUsers — загружает пользователей через COPY. COPY не умеет RETURNING, поэтому сгенерированные
+// идентификаторы и даты дочитываются отдельным запросом по email: среди активных пользователей он уникален,
+// а в той же транзакции других вставок этих адресов быть не может
+func copyUsers(ctx context.Context, tx pgx.Tx, users []model.User) error {
+	_, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"users"},
+		[]string{"username", "email", "password_hash"},
+		pgx.CopyFromSlice(len(users), func(i int) ([]any, error) {
+			return []any{users[i].Username, users[i].Email, users[i].PasswordHash}, nil
+		}),
+	)
+	if err != nil {
+		// Нарушение уникальности при COPY приходит той же ошибкой Postgres, что и при INSERT
+		return translateError(err)
+	}
+
+	emails := make([]string, len(users))
+	byEmail := make(map[string]int, len(users))
+	for i := range users {
+		emails[i] = users[i].Email
+		byEmail[users[i].Email] = i
+	}
+
+	rows, err := tx.Query(ctx, `SELECT `+userColumns+` FROM users WHERE email = ANY($1) AND deleted_at IS NULL`, emails)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения загруженных пользователей: %w", err)
+	}
+	defer rows.Close()
+
+	found := 0
+	for rows.Next() {
+		var user model.User
+		if err := scanUser(rows, &user); err != nil {
+			return fmt.Errorf("ошибка чтения строки пользователя: %w", err)
+		}
+		// Сохраняем порядок исходного пакета и пароль-хеш, которого нет в userColumns
+		i := byEmail[user.Email]
+		user.PasswordHash = users[i].PasswordHash
+		users[i] = user
+		found++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения загруженных пользователей: %w", err)
+	}
+	if found != len(users) {
+		return fmt.Errorf("после COPY найдено %d пользователей из %d", found, len(users))
+	}
+
+	return nil
+}
+
+func (r *PostgresUserRepository) GetByID(ctx context.Context, id uuid.UUID) (model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND deleted_at IS NULL`
+
+	var user model.User
+	if err := scanUser(r.db.QueryRow(ctx, query, id), &user); err != nil {
+		return model.User{}, translateError(err)
+	}
+
+	return user, nil
+}
+
+func (r *PostgresUserRepository) List(ctx context.Context, params ListParams) ([]model.User, error) {
+	where, args := whereClause(params.ListFilter)
+	if params.After != nil {
+		var condition string
+		condition, args = keysetCondition(params.Sort, *params.After, args)
+		where = appendCondition(where, condition)
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users` + where +
+		` ORDER BY ` + orderByClause(params.Sort) +
+		` LIMIT ` + placeholder(len(args)+1) + ` OFFSET ` + placeholder(len(args)+2)
+	rows, err := r.db.Query(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выборки пользователей: %w", err)
+	}
+	defer rows.Close()
+
+	// Инициализируем пустой срез, чтобы при отсутствии записей вернуть [], а не null
+	users := make([]model.User, 0)
+	for rows.Next() {
+		var user model.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("ошибка чтения строки пользователя: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка выборки пользователей: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *PostgresUserRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	var total int64
+	where, args := whereClause(filter)
+	if err := r.db.QueryRow(ctx, `SELECT count(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("ошибка подсчёта пользователей: %w", err)
+	}
+
+	return total, nil
+}
+
+func (r *PostgresUserRepository) Update(ctx context.Context, user *model.User) error {
+	// updated_at меняется при каждом обновлении; пустой хеш оставляет прежний пароль
+	query := `UPDATE users SET username = $1, email = $2,
+		password_hash = COALESCE(NULLIF($4, ''), password_hash), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND deleted_at IS NULL RETURNING ` + userColumns
+	if err := scanUser(r.db.QueryRow(ctx, query, user.Username, user.Email, user.ID, user.PasswordHash), user); err != nil {
+		return translateError(err)
+	}
+
+	return nil
+}
+
+func (r *PostgresUserRepository) Patch(ctx context.Context, id uuid.UUID, patch UserPatch) (model.User, error) {
+	// nil-указатель передаётся как NULL, и COALESCE оставляет прежнее значение столбца
+	query := `UPDATE users SET username = COALESCE($2, username), email = COALESCE($3, email),
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL RETURNING ` + userColumns
+
+	var user model.User
+	if err := scanUser(r.db.QueryRow(ctx, query, id, patch.Username, patch.Email), &user); err != nil {
+		return model.User{}, translateError(err)
+	}
+
+	return user, nil
+}
+
+func (r *PostgresUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	// Запись не удаляется физически, а помечается временем удаления — она нужна для аудита
+	query := `UPDATE users SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL`
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления пользователя: %w", err)
+	}
+
+	// Если ни одна строка не затронута, значит активного пользователя с таким идентификатором нет
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresUserRepository) Restore(ctx context.Context, id uuid.UUID) (model.User, error) {
+	var user model.User
+	err := r.InTx(ctx, func(tx pgx.Tx) error {
+		// Частичные уникальные индексы учитывают только активных пользователей, поэтому занятый
+		// за это время email или имя проявится здесь нарушением уникальности
+		query := `UPDATE users SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND deleted_at IS NOT NULL RETURNING ` + userColumns
+		err := scanUser(tx.QueryRow(ctx, query, id), &user)
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return translateError(err)
+		}
+
+		// Ни одна строка не изменилась: различаем отсутствующего и не удалённого пользователя
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return fmt.Errorf("ошибка проверки пользователя: %w", err)
+		}
+		if !exists {
+			return ErrNotFound
+		}
+
+		return ErrNotDeleted
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+func (r *PostgresUserRepository) DeleteMatching(ctx context.Context, filter ListFilter) ([]uuid.UUID, error) {
+	filter.IncludeDeleted = false
+	where, args := whereClause(filter)
+
+	var ids []uuid.UUID
+	err := r.InTx(ctx, func(tx pgx.Tx) error {
+		query := `UPDATE users SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP` + where + ` RETURNING id`
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("ошибка удаления пользователей: %w", err)
+		}
+
+		ids, err = pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil {
+			return fmt.Errorf("ошибка удаления пользователей: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// InTx выполняет fn в транзакции: фиксирует её, если fn завершилась успешно, и откатывает
+// при ошибке или панике. Ошибка отката только логируется и не подменяет исходную ошибку
+func (r *PostgresUserRepository) InTx(ctx context.Context, fn func(tx pgx.Tx) error) (err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			rollback(ctx, tx)
+			panic(p)
+		}
+		if err != nil {
+			rollback(ctx, tx)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("не удалось зафиксировать транзакцию: %w", err)
+	}
+
+	return nil
+}
+
+// rollback откатывает транзакцию и логирует ошибку отката, не возвращая её
+func rollback(ctx context.Context, tx pgx.Tx) {
+	// Контекст запроса мог быть уже отменён, а откат всё равно нужно отправить
+	if err := tx.Rollback(context.WithoutCancel(ctx)); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		slog.Error("Ошибка отката транзакции", "error", err)
+	}
+}
+
+// whereClause строит условие WHERE по фильтру списка. Значения фильтров передаются только
+// как параметры запроса, в текст SQL попадают лишь их плейсхолдеры
+func whereClause(filter ListFilter) (string, []any) {
+	var conditions []string
+	var args []any
+
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, `deleted_at IS NULL`)
+	}
+	if filter.UsernameQuery != "" {
+		args = append(args, "%"+escapeLike(filter.UsernameQuery)+"%")
+		conditions = append(conditions, `username ILIKE `+placeholder(len(args))+` ESCAPE '\'`)
+	}
+	// Email хранится нормализованным, поэтому точное сравнение использует уникальный индекс
+	if filter.Email != "" {
+		args = append(args, filter.Email)
+		conditions = append(conditions, `email = `+placeholder(len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return ` WHERE ` + strings.Join(conditions, " AND "), args
+}
+
+// keysetCondition строит условие "строго после курсора" в порядке сортировки sort.
+// Сравнение пар (ключ, id) совпадает с ORDER BY orderByClause, поэтому страницы не пересекаются
+// и не пропускают строки, даже если между запросами вставлены новые записи
+func keysetCondition(sort Sort, after Cursor, args []any) (string, []any) {
+	column, value := "created_at", any(after.CreatedAt)
+	if sort.Field == SortByUsername {
+		column, value = "username", after.Username
+	}
+
+	operator := ">"
+	if sort.Desc {
+		operator = "<"
+	}
+
+	args = append(args, value, after.ID)
+	condition := `(` + column + `, id) ` + operator + ` (` + placeholder(len(args)-1) + `, ` + placeholder(len(args)) + `)`
+
+	return condition, args
+}
+
+// appendCondition добавляет условие к уже построенному WHERE или начинает новое
+func appendCondition(where, condition string) string {
+	if where == "" {
+		return ` WHERE ` + condition
+	}
+
+	return where + ` AND ` + condition
+}
+
+// orderByClause строит выражение ORDER BY. Имя колонки подставляется в текст запроса,
+// поэтому берётся только из фиксированного списка, а не из пользовательского ввода.
+// Идентификатор добавлен вторым ключом, чтобы порядок был стабильным при равных значениях
+func orderByClause(sort Sort) string {
+	column := "created_at"
+	if sort.Field == SortByUsername {
+		column = "username"
+	}
+
+	direction := "ASC"
+	if sort.Desc {
+		direction = "DESC"
+	}
+
+	return column + " " + direction + ", id " + direction
+}
+
+// likeEscaper экранирует спецсимволы шаблона LIKE, чтобы они искались как обычные символы
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike превращает пользовательский ввод в буквальную подстроку для LIKE/ILIKE
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// placeholder возвращает позиционный параметр Postgres с номером n: $1, $2, ...
+func placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// scanUser читает строку с колонками userColumns в user
+func scanUser(row pgx.Row, user *model.User) error {
+	return row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt)
+}
+
+// translateError переводит ошибки pgx в ошибки репозитория, на которые опираются обработчики
+func translateError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		switch pgErr.ConstraintName {
+		case emailUniqueConstraint:
+			return ErrEmailTaken
+		case usernameUniqueConstraint:
+			return ErrUsernameTaken
+		}
+	}
+
+	return err
+}
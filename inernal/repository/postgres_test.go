@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+// batchUsers — n пользователей с уникальными именами и email; prefix отличает пакеты друг от друга
+func batchUsers(prefix string, n int) []model.User {
+	users := make([]model.User, n)
+	for i := range users {
+		users[i] = model.User{
+			Username:     fmt.Sprintf("%s_%d", prefix, i),
+			Email:        fmt.Sprintf("%s_%d@example.com", prefix, i),
+			PasswordHash: "hash",
+		}
+	}
+
+	return users
+}
+
+func TestPostgresCreateBatch(t *testing.T) {
+	repo := NewPostgresUserRepository(testPool(t), RetryPolicy{})
+	ctx := context.Background()
+
+	// Размеры по обе стороны порога: пакет INSERT ... RETURNING и COPY с дочитыванием по email
+	for _, size := range []int{copyBatchThreshold - 1, copyBatchThreshold} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			users := batchUsers(fmt.Sprintf("batch%d", size), size)
+			if err := repo.CreateBatch(ctx, users); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, user := range users {
+				if user.CreatedAt.IsZero() || user.Username != fmt.Sprintf("batch%d_%d", size, i) {
+					t.Fatalf("элемент %d: не заполнены сгенерированные поля или нарушен порядок: %+v", i, user)
+				}
+				got, err := repo.GetByID(ctx, user.ID)
+				if err != nil {
+					t.Fatalf("элемент %d не найден по ID из пакета: %v", i, err)
+				}
+				if got.Email != user.Email {
+					t.Fatalf("по ID элемента %d найден %s, ожидался %s", i, got.Email, user.Email)
+				}
+			}
+		})
+	}
+}
+
+func TestPostgresCreateBatchConflictRollsBack(t *testing.T) {
+	repo := NewPostgresUserRepository(testPool(t), RetryPolicy{})
+	ctx := context.Background()
+
+	for _, size := range []int{copyBatchThreshold - 1, copyBatchThreshold} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			users := batchUsers(fmt.Sprintf("dup%d", size), size)
+			users[size-1].Email = users[0].Email
+
+			if err := repo.CreateBatch(ctx, users); !errors.Is(err, ErrEmailTaken) {
+				t.Fatalf("CreateBatch вернул %v, ожидался ErrEmailTaken", err)
+			}
+			count, err := repo.Count(ctx, ListFilter{UsernameQuery: fmt.Sprintf("dup%d_", size)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 0 {
+				t.Fatalf("после ошибки сохранено %d пользователей", count)
+			}
+		})
+	}
+}
+
+// benchmarkBatchSize — размер пакета для сравнения путей вставки: типичный крупный импорт
+const benchmarkBatchSize = 1000
+
+// benchmarkCreateBatch — измеряет вставку пакета функцией insert. Каждая итерация откатывается,
+// чтобы таблица не росла между итерациями и результаты были сравнимы
+func benchmarkCreateBatch(b *testing.B, insert func(context.Context, pgx.Tx, []model.User) error) {
+	pool := testPool(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := range b.N {
+		users := batchUsers(fmt.Sprintf("bench%d", i), benchmarkBatchSize)
+
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := insert(ctx, tx, users); err != nil {
+			_ = tx.Rollback(ctx)
+			b.Fatal(err)
+		}
+		if err := tx.Rollback(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*benchmarkBatchSize)/b.Elapsed().Seconds(), "rows/s")
+}
+
+func BenchmarkCreateBatchCopy(b *testing.B) {
+	benchmarkCreateBatch(b, copyUsers)
+}
+
+func BenchmarkCreateBatchInsert(b *testing.B) {
+	benchmarkCreateBatch(b, insertUsers)
+}
+
+func TestPostgresInTxRollsBack(t *testing.T) {
+	repo := NewPostgresUserRepository(testPool(t), RetryPolicy{})
+	ctx := context.Background()
+
+	// insertHalf — первая запись «многошаговой» операции, после которой случается сбой
+	insertHalf := func(tx pgx.Tx, username string) error {
+		return insertUsers(ctx, tx, batchUsers(username, 1))
+	}
+	expectNothingSaved := func(t *testing.T, username string) {
+		t.Helper()
+
+		count, err := repo.Count(ctx, ListFilter{UsernameQuery: username})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatalf("после отката сохранено %d пользователей", count)
+		}
+	}
+
+	t.Run("ошибка", func(t *testing.T) {
+		errFailed := errors.New("сбой второго шага")
+		err := repo.InTx(ctx, func(tx pgx.Tx) error {
+			if err := insertHalf(tx, "txerror"); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		// Ошибка отката не подменяет исходную ошибку
+		if !errors.Is(err, errFailed) {
+			t.Fatalf("InTx вернул %v, ожидалась исходная ошибка", err)
+		}
+		expectNothingSaved(t, "txerror")
+	})
+
+	t.Run("паника", func(t *testing.T) {
+		func() {
+			defer func() {
+				if p := recover(); p == nil {
+					t.Fatal("паника внутри транзакции не передана дальше")
+				}
+			}()
+			_ = repo.InTx(ctx, func(tx pgx.Tx) error {
+				if err := insertHalf(tx, "txpanic"); err != nil {
+					return err
+				}
+				panic("сбой второго шага")
+			})
+		}()
+		expectNothingSaved(t, "txpanic")
+	})
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+// redisKeyPrefix — префикс ключей пользователей в Redis, чтобы не пересекаться с другими данными
+const redisKeyPrefix = "users:"
+
+// RedisCache — кэш пользователей в Redis, общий для всех экземпляров сервиса.
+// Пока Redis недоступен, чтение считается промахом, а запросы идут в базу
+type RedisCache struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+func NewRedisCache(client redis.UniversalClient, ttl time.Duration) *RedisCache {
+	return &RedisCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (c *RedisCache) Get(ctx context.Context, id uuid.UUID) (model.User, bool) {
+	data, err := c.client.Get(ctx, redisKey(id)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			slog.Warn("Кэш Redis недоступен, читаем из базы", "user_id", id, "error", err)
+		}
+		return model.User{}, false
+	}
+
+	// GetByID не читает хеш пароля, поэтому JSON-представления модели достаточно
+	var user model.User
+	if err := json.Unmarshal(data, &user); err != nil {
+		slog.Warn("Некорректная запись в кэше Redis", "user_id", id, "error", err)
+		return model.User{}, false
+	}
+
+	return user, true
+}
+
+func (c *RedisCache) Put(ctx context.Context, user model.User) {
+	data, err := json.Marshal(user)
+	if err != nil {
+		slog.Warn("Не удалось сериализовать пользователя для кэша", "user_id", user.ID, "error", err)
+		return
+	}
+
+	if err := c.client.Set(ctx, redisKey(user.ID), data, c.ttl).Err(); err != nil {
+		slog.Warn("Не удалось записать пользователя в кэш Redis", "user_id", user.ID, "error", err)
+	}
+}
+
+func (c *RedisCache) Remove(ctx context.Context, id uuid.UUID) {
+	// Несброшенная запись останется устаревшей до истечения TTL, поэтому это ошибка, а не предупреждение
+	if err := c.client.Del(ctx, redisKey(id)).Err(); err != nil {
+		slog.Error("Не удалось сбросить пользователя в кэше Redis", "user_id", id, "error", err)
+	}
+}
+
+// redisKey — ключ пользователя в Redis
+func redisKey(id uuid.UUID) string {
+	return redisKeyPrefix + id.String()
+}
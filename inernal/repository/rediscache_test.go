@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+// newTestRedisCache — кэш поверх Redis в памяти теста с теми же короткими таймаутами, что и в сервисе
+func newTestRedisCache(t *testing.T) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{
+		Addr:        server.Addr(),
+		DialTimeout: 100 * time.Millisecond,
+		ReadTimeout: 100 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewRedisCache(client, time.Minute), server
+}
+
+func TestRedisCacheHitAndMiss(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedisCache(t)
+	user := model.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com", CreatedAt: time.Now().UTC()}
+
+	if _, ok := cache.Get(ctx, user.ID); ok {
+		t.Fatal("пустой кэш вернул пользователя")
+	}
+
+	cache.Put(ctx, user)
+	got, ok := cache.Get(ctx, user.ID)
+	if !ok {
+		t.Fatal("промах сразу после записи")
+	}
+	if got.ID != user.ID || got.Email != user.Email || !got.CreatedAt.Equal(user.CreatedAt) {
+		t.Fatalf("из кэша прочитан %+v, ожидался %+v", got, user)
+	}
+	if ttl := server.TTL(redisKey(user.ID)); ttl != time.Minute {
+		t.Fatalf("TTL ключа %s, ожидалась минута", ttl)
+	}
+
+	// Запись устаревает по TTL
+	server.FastForward(time.Minute)
+	if _, ok := cache.Get(ctx, user.ID); ok {
+		t.Fatal("запись не устарела по TTL")
+	}
+}
+
+func TestRedisCacheRemove(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedisCache(t)
+	user := model.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com"}
+
+	cache.Put(ctx, user)
+	cache.Remove(ctx, user.ID)
+	if server.Exists(redisKey(user.ID)) {
+		t.Fatal("ключ не удалён из Redis")
+	}
+	if _, ok := cache.Get(ctx, user.ID); ok {
+		t.Fatal("после сброса кэш вернул пользователя")
+	}
+}
+
+func TestRedisCacheCorruptEntry(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedisCache(t)
+	id := uuid.New()
+
+	if err := server.Set(redisKey(id), "not-json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(ctx, id); ok {
+		t.Fatal("некорректная запись должна считаться промахом")
+	}
+}
+
+func TestCachedRepositoryWithRedis(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedisCache(t)
+	users := NewCachedUserRepository(NewMemoryUserRepository(), cache)
+
+	user := model.User{Username: "alice", Email: "alice@example.com"}
+	if err := users.Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+
+	// Промах: пользователь читается из хранилища и попадает в кэш
+	if _, err := users.GetByID(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Exists(redisKey(user.ID)) {
+		t.Fatal("после чтения пользователь не попал в кэш")
+	}
+
+	// Изменение сбрасывает запись, и следующее чтение видит новые данные
+	user.Email = "alice@example.org"
+	if err := users.Update(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists(redisKey(user.ID)) {
+		t.Fatal("обновление не сбросило запись в кэше")
+	}
+	got, err := users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != "alice@example.org" {
+		t.Fatalf("прочитан устаревший email %q", got.Email)
+	}
+
+	// Удаление тоже сбрасывает запись: удалённого пользователя больше не видно
+	if err := users.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists(redisKey(user.ID)) {
+		t.Fatal("удаление не сбросило запись в кэше")
+	}
+	if _, err := users.GetByID(ctx, user.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("после удаления GetByID вернул %v, ожидался ErrNotFound", err)
+	}
+}
+
+func TestCachedRepositoryRedisUnavailable(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedisCache(t)
+	users := NewCachedUserRepository(NewMemoryUserRepository(), cache)
+
+	user := model.User{Username: "alice", Email: "alice@example.com"}
+	if err := users.Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+
+	// Недоступный Redis не ломает запросы: чтение и изменения идут мимо кэша
+	server.Close()
+	if _, err := users.GetByID(ctx, user.ID); err != nil {
+		t.Fatalf("без Redis GetByID вернул ошибку: %v", err)
+	}
+	user.Username = "alice2"
+	if err := users.Update(ctx, &user); err != nil {
+		t.Fatalf("без Redis Update вернул ошибку: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+var (
+	// ErrNotFound возвращается, когда пользователя с указанным идентификатором нет
+	ErrNotFound = errors.New("пользователь не найден")
+	// ErrEmailTaken возвращается при попытке сохранить email, уже занятый другим пользователем
+	ErrEmailTaken = errors.New("email уже занят")
+	// ErrUsernameTaken возвращается при попытке сохранить имя, уже занятое другим пользователем
+	ErrUsernameTaken = errors.New("имя пользователя уже занято")
+	// ErrNotDeleted возвращается при попытке восстановить пользователя, который не удалён
+	ErrNotDeleted = errors.New("пользователь не удалён")
+)
+
+// SortField — поле, по которому можно упорядочить список пользователей
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortByUsername  SortField = "username"
+)
+
+// Sort — порядок выборки; нулевое значение означает сортировку по created_at по возрастанию
+type Sort struct {
+	Field SortField
+	Desc  bool
+}
+
+// Cursor — позиция в упорядоченном списке: ключ сортировки и идентификатор последнего элемента страницы.
+// Из CreatedAt и Username используется только поле, по которому идёт сортировка
+type Cursor struct {
+	CreatedAt time.Time
+	Username  string
+	ID        uuid.UUID
+}
+
+// ListFilter — условия отбора пользователей, общие для выборки и подсчёта
+type ListFilter struct {
+	// IncludeDeleted — включать мягко удалённых пользователей
+	IncludeDeleted bool
+	// UsernameQuery — подстрока имени пользователя без учёта регистра; пустая строка отключает поиск
+	UsernameQuery string
+	// Email — точное совпадение с email в нижнем регистре, как он хранится; пустая строка отключает фильтр
+	Email string
+}
+
+// ListParams — параметры выборки страницы пользователей
+type ListParams struct {
+	ListFilter
+	Limit  int
+	Offset int
+	Sort   Sort
+	// After — если задан, выборка начинается сразу после этой позиции (keyset-пагинация) вместо Offset
+	After *Cursor
+}
+
+// UserPatch — частичное обновление пользователя: nil означает, что поле не меняется
+type UserPatch struct {
+	Username *string
+	Email    *string
+}
+
+// UserRepository — хранилище пользователей. Обработчики зависят только от этого интерфейса,
+// поэтому реализацию можно подменить, не трогая HTTP-слой
+type UserRepository interface {
+	// Create сохраняет пользователя и заполняет сгенерированные поля: ID и CreatedAt
+	Create(ctx context.Context, user *model.User) error
+	// CreateBatch сохраняет всех пользователей атомарно: либо все, либо ни одного.
+	// Сгенерированные поля заполняются в элементах users
+	CreateBatch(ctx context.Context, users []model.User) error
+	// GetByID возвращает активного пользователя по идентификатору или ErrNotFound
+	GetByID(ctx context.Context, id uuid.UUID) (model.User, error)
+	// List возвращает страницу пользователей, упорядоченных по params.Sort
+	List(ctx context.Context, params ListParams) ([]model.User, error)
+	// Count возвращает число пользователей, подходящих под filter
+	Count(ctx context.Context, filter ListFilter) (int64, error)
+	// Update заменяет username и email активного пользователя с user.ID, а также хеш пароля,
+	// если PasswordHash не пуст; обновляет UpdatedAt
+	// и заполняет user актуальными данными
+	Update(ctx context.Context, user *model.User) error
+	// Patch меняет у активного пользователя только заданные в patch поля, обновляет UpdatedAt
+	// и возвращает пользователя с актуальными данными или ErrNotFound
+	Patch(ctx context.Context, id uuid.UUID, patch UserPatch) (model.User, error)
+	// Delete мягко удаляет пользователя, проставляя DeletedAt, или возвращает ErrNotFound,
+	// если активного пользователя с таким идентификатором нет
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Restore снимает пометку об удалении и возвращает восстановленного пользователя. Возвращает ErrNotFound,
+	// если пользователя нет, ErrNotDeleted, если он не удалён, и ErrEmailTaken или ErrUsernameTaken,
+	// если его email или имя с тех пор занял другой активный пользователь
+	Restore(ctx context.Context, id uuid.UUID) (model.User, error)
+	// DeleteMatching мягко удаляет всех активных пользователей, подходящих под filter, одной транзакцией
+	// и возвращает их идентификаторы. IncludeDeleted игнорируется: удалённых повторно не удаляем
+	DeleteMatching(ctx context.Context, filter ListFilter) ([]uuid.UUID, error)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Коды ошибок Postgres (SQLSTATE), после которых транзакцию можно безопасно выполнить заново:
+// сервер сам откатил её целиком
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+)
+
+// RetryPolicy — повтор операций с базой при временных ошибках
+type RetryPolicy struct {
+	// Attempts — всего попыток, включая первую; 1 и меньше отключают повторы
+	Attempts int
+	// BaseDelay — пауза перед первым повтором; каждая следующая пауза вдвое длиннее
+	BaseDelay time.Duration
+}
+
+// do выполняет fn, повторяя её при временных ошибках, пока не кончатся попытки или не отменится ctx.
+// Остальные ошибки, например нарушение уникальности, возвращаются сразу
+func (p RetryPolicy) do(ctx context.Context, operation string, fn func() error) error {
+	delay := p.BaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || !isRetryable(err) {
+			return err
+		}
+
+		slog.Warn("Временная ошибка базы данных, повторяем операцию",
+			"operation", operation, "attempt", attempt, "retry_in", delay.String(), "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// isRetryable сообщает, может ли повтор операции завершиться успешно: конфликт сериализации,
+// взаимоблокировка или сбой соединения до отправки запроса на сервер
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == serializationFailureCode || pgErr.Code == deadlockDetectedCode
+	}
+
+	// SafeToRetry гарантирует, что сервер запрос не получил, поэтому повторная вставка не создаст дубль
+	return pgconn.SafeToRetry(err)
+}
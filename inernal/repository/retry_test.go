@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// failingOp — операция, которая первые failures вызовов возвращает err, а затем завершается успешно
+type failingOp struct {
+	err      error
+	failures int
+	calls    int
+}
+
+func (o *failingOp) run() error {
+	o.calls++
+	if o.calls <= o.failures {
+		return o.err
+	}
+
+	return nil
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond}
+	serialization := fmt.Errorf("вставка: %w", &pgconn.PgError{Code: serializationFailureCode})
+
+	tests := []struct {
+		name      string
+		err       error
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "конфликт сериализации повторяется", err: serialization, failures: 2, wantCalls: 3},
+		{name: "взаимоблокировка повторяется", err: &pgconn.PgError{Code: deadlockDetectedCode}, failures: 1, wantCalls: 2},
+		{name: "попытки кончились", err: serialization, failures: 5, wantCalls: 3, wantErr: true},
+		{name: "нарушение уникальности не повторяется", err: &pgconn.PgError{Code: uniqueViolationCode}, failures: 5,
+			wantCalls: 1, wantErr: true},
+		{name: "прочие ошибки не повторяются", err: errors.New("сбой"), failures: 5, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := &failingOp{err: tt.err, failures: tt.failures}
+			err := policy.do(context.Background(), "test", op.run)
+			if (err != nil) != tt.wantErr || op.calls != tt.wantCalls {
+				t.Fatalf("err=%v, вызовов %d; ожидалось err=%v, вызовов %d", err, op.calls, tt.wantErr, tt.wantCalls)
+			}
+			if err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("вернулась %v вместо ошибки операции", err)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDisabledAndCanceled(t *testing.T) {
+	serialization := &pgconn.PgError{Code: serializationFailureCode}
+
+	// Attempts=0 — повторов нет
+	op := &failingOp{err: serialization, failures: 1}
+	if err := (RetryPolicy{}).do(context.Background(), "test", op.run); err == nil || op.calls != 1 {
+		t.Fatalf("без повторов: err=%v, вызовов %d", err, op.calls)
+	}
+
+	// Отменённый контекст прерывает паузу перед повтором
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	op = &failingOp{err: serialization, failures: 1}
+	if err := (RetryPolicy{Attempts: 3, BaseDelay: time.Hour}).do(ctx, "test", op.run); err == nil || op.calls != 1 {
+		t.Fatalf("после отмены: err=%v, вызовов %d", err, op.calls)
+	}
+}
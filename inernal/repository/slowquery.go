@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/middleware"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/tracing"
+)
+
+// maxLoggedSQLLength — сколько символов текста запроса попадает в лог медленных запросов
+const maxLoggedSQLLength = 500
+
+// SlowQueryLogger — трейсер pgx, логирующий запросы, которые выполнялись дольше порога.
+// Быстрые запросы не логируются вовсе, чтобы не засорять лог
+type SlowQueryLogger struct {
+	threshold time.Duration
+}
+
+// NewSlowQueryLogger создаёт трейсер, логирующий запросы дольше threshold
+func NewSlowQueryLogger(threshold time.Duration) *SlowQueryLogger {
+	return &SlowQueryLogger{
+		threshold: threshold,
+	}
+}
+
+// slowQueryKey — ключ контекста, под которым TraceQueryStart передаёт запрос в TraceQueryEnd
+type slowQueryKey struct{}
+
+// slowQueryStart — текст запроса и время его начала
+type slowQueryStart struct {
+	sql   string
+	start time.Time
+}
+
+func (l *SlowQueryLogger) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, slowQueryKey{}, slowQueryStart{sql: data.SQL, start: time.Now()})
+}
+
+// TraceQueryEnd вызывается после чтения всех строк результата, поэтому длительность включает и его
+func (l *SlowQueryLogger) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	query, ok := ctx.Value(slowQueryKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(query.start)
+	if duration < l.threshold {
+		return
+	}
+
+	attrs := []any{"duration", duration.String(), "threshold", l.threshold.String(), "sql", truncateSQL(query.sql)}
+	if id := middleware.RequestIDFromContext(ctx); id != "" {
+		attrs = append(attrs, "request_id", id)
+	}
+	if data.Err != nil {
+		attrs = append(attrs, "error", data.Err)
+	}
+	slog.Warn("Медленный запрос к базе данных", append(attrs, tracing.LogAttrs(ctx)...)...)
+}
+
+// truncateSQL схлопывает пробелы и переводы строк запроса и обрезает его до maxLoggedSQLLength символов
+func truncateSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if utf8.RuneCountInString(sql) <= maxLoggedSQLLength {
+		return sql
+	}
+
+	return string([]rune(sql)[:maxLoggedSQLLength]) + "…"
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/middleware"
+)
+
+// captureSlowQueries — пишет лог в формате JSON в буфер до конца теста
+func captureSlowQueries(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return &logs
+}
+
+// traceQuery — проводит запрос sql через трейсер, будто он выполнялся duration
+func traceQuery(ctx context.Context, tracer *SlowQueryLogger, sql string, duration time.Duration) {
+	ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql})
+	time.Sleep(duration)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+}
+
+func TestSlowQueryLogger(t *testing.T) {
+	logs := captureSlowQueries(t)
+	tracer := NewSlowQueryLogger(20 * time.Millisecond)
+	ctx := middleware.WithRequestID(context.Background(), "req-123")
+
+	// Быстрый запрос не логируется
+	traceQuery(ctx, tracer, "SELECT 1", 0)
+	if logs.Len() != 0 {
+		t.Fatalf("быстрый запрос попал в лог: %s", logs)
+	}
+
+	traceQuery(ctx, tracer, "SELECT pg_sleep(1)\n\t  FROM users", 30*time.Millisecond)
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("в логе не одна запись JSON: %v\n%s", err, logs)
+	}
+	duration, err := time.ParseDuration(entry["duration"].(string))
+	if err != nil || duration < 20*time.Millisecond {
+		t.Fatalf("duration = %v, ожидалось не меньше порога", entry["duration"])
+	}
+	// Пробелы и переводы строк схлопнуты, запрос связан с HTTP-запросом
+	if entry["level"] != "WARN" || entry["sql"] != "SELECT pg_sleep(1) FROM users" || entry["request_id"] != "req-123" {
+		t.Fatalf("запись о медленном запросе: %v", entry)
+	}
+}
+
+func TestTruncateSQL(t *testing.T) {
+	long := "SELECT " + strings.Repeat("имя, ", maxLoggedSQLLength)
+	got := truncateSQL(long)
+	if !strings.HasSuffix(got, "…") || len([]rune(got)) != maxLoggedSQLLength+1 {
+		t.Fatalf("длинный запрос обрезан до %d символов: %q", len([]rune(got)), got)
+	}
+	if got := truncateSQL("SELECT id\n  FROM users"); got != "SELECT id FROM users" {
+		t.Fatalf("truncateSQL = %q", got)
+	}
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/migrator"
+	"github.com/olezhek28/docker-compose-tutorial/migrations"
+)
+
+func TestMain(m *testing.M) {
+	// Хранилища и кэш логируют деградацию; в выводе тестов это только мешает
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// testDBURIEnv — переменная со строкой подключения к Postgres для тестов, которым нужна настоящая база.
+// Без неё такие тесты пропускаются
+const testDBURIEnv = "TEST_DB_URI"
+
+// testPool — подключается к базе из TEST_DB_URI, создаёт для теста отдельную схему и применяет в ней
+// все миграции. Схема удаляется по завершении теста, поэтому тесты не мешают друг другу и данным в базе
+func testPool(tb testing.TB) *pgxpool.Pool {
+	tb.Helper()
+
+	uri := os.Getenv(testDBURIEnv)
+	if uri == "" {
+		tb.Skipf("%s не задана, тест с Postgres пропущен", testDBURIEnv)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	admin, err := pgx.Connect(ctx, uri)
+	if err != nil {
+		tb.Fatalf("подключение к %s: %v", testDBURIEnv, err)
+	}
+	defer admin.Close(ctx)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		tb.Fatalf("создание схемы: %v", err)
+	}
+	tb.Cleanup(func() {
+		conn, err := pgx.Connect(context.Background(), uri)
+		if err != nil {
+			tb.Logf("схема %s не удалена: %v", schema, err)
+			return
+		}
+		defer conn.Close(context.Background())
+		if _, err := conn.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			tb.Logf("схема %s не удалена: %v", schema, err)
+		}
+	})
+
+	cfg, err := pgxpool.ParseConfig(uri)
+	if err != nil {
+		tb.Fatalf("разбор %s: %v", testDBURIEnv, err)
+	}
+	setSearchPath := func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, fmt.Sprintf("SET search_path TO %s, public", schema))
+		return err
+	}
+	cfg.AfterConnect = setSearchPath
+
+	sqlDB := stdlib.OpenDB(*cfg.ConnConfig.Copy(), stdlib.OptionAfterConnect(setSearchPath))
+	defer sqlDB.Close()
+	if err := migrator.NewMigratorFS(sqlDB, migrations.FS).Up(); err != nil {
+		tb.Fatalf("миграции: %v", err)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		tb.Fatalf("пул соединений: %v", err)
+	}
+	tb.Cleanup(pool.Close)
+
+	return pool
+}
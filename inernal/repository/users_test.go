@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	testUserRepository(t, NewMemoryUserRepository())
+}
+
+func TestPostgresUserRepository(t *testing.T) {
+	testUserRepository(t, NewPostgresUserRepository(testPool(t), RetryPolicy{}))
+}
+
+// createTestUser — сохраняет пользователя с заданными именем и email
+func createTestUser(t *testing.T, repo UserRepository, username, email string) model.User {
+	t.Helper()
+
+	user := model.User{Username: username, Email: email, PasswordHash: "hash"}
+	if err := repo.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+// testUserRepository — общие проверки поведения для всех реализаций UserRepository
+func testUserRepository(t *testing.T, repo UserRepository) {
+	ctx := context.Background()
+
+	t.Run("создание", func(t *testing.T) {
+		user := createTestUser(t, repo, "created", "created@example.com")
+		if user.ID == uuid.Nil || user.ID.Version() != 4 {
+			t.Fatalf("выдан идентификатор %s, ожидался случайный UUID", user.ID)
+		}
+
+		got, err := repo.GetByID(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != user.ID || got.Email != "created@example.com" {
+			t.Fatalf("по ID найден %+v", got)
+		}
+	})
+
+	t.Run("поиск по имени", func(t *testing.T) {
+		createTestUser(t, repo, "search_one", "search_one@example.com")
+		createTestUser(t, repo, "searchXtwo", "search_two@example.com")
+
+		count := func(query string) int64 {
+			t.Helper()
+
+			count, err := repo.Count(ctx, ListFilter{UsernameQuery: query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return count
+		}
+		if got := count("SEARCH"); got != 2 {
+			t.Fatalf("поиск без учёта регистра нашёл %d, ожидалось 2", got)
+		}
+		if got := count("search-nobody"); got != 0 {
+			t.Fatalf("поиск без совпадений нашёл %d", got)
+		}
+		// Спецсимволы LIKE ищутся буквально: _ не заменяет любой символ, % не совпадает со всем
+		if got := count("search_"); got != 1 {
+			t.Fatalf("поиск search_ нашёл %d, ожидался только search_one", got)
+		}
+		if got := count("%"); got != 0 {
+			t.Fatalf("поиск %% нашёл %d, ожидалось 0", got)
+		}
+	})
+
+	t.Run("поиск по email", func(t *testing.T) {
+		createTestUser(t, repo, "email_exact", "exact@example.com")
+		createTestUser(t, repo, "email_prefix", "exact@example.com.au")
+
+		users, err := repo.List(ctx, ListParams{ListFilter: ListFilter{Email: "exact@example.com"}, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 1 || users[0].Username != "email_exact" {
+			t.Fatalf("поиск по email нашёл %+v, ожидался только email_exact", users)
+		}
+	})
+
+	t.Run("конфликт уникальности", func(t *testing.T) {
+		createTestUser(t, repo, "unique", "unique@example.com")
+
+		taken := model.User{Username: "unique", Email: "unique2@example.com", PasswordHash: "hash"}
+		if err := repo.Create(ctx, &taken); !errors.Is(err, ErrUsernameTaken) {
+			t.Fatalf("занятое имя: Create вернул %v, ожидался ErrUsernameTaken", err)
+		}
+		taken = model.User{Username: "unique2", Email: "unique@example.com", PasswordHash: "hash"}
+		if err := repo.Create(ctx, &taken); !errors.Is(err, ErrEmailTaken) {
+			t.Fatalf("занятый email: Create вернул %v, ожидался ErrEmailTaken", err)
+		}
+	})
+
+	t.Run("курсор", func(t *testing.T) {
+		for i := range 5 {
+			createTestUser(t, repo, fmt.Sprintf("keyset_%d", i), fmt.Sprintf("keyset_%d@example.com", i))
+		}
+
+		for _, sort := range []Sort{{Field: SortByUsername}, {Field: SortByCreatedAt, Desc: true}} {
+			var names []string
+			var after *Cursor
+			for {
+				page, err := repo.List(ctx, ListParams{
+					ListFilter: ListFilter{UsernameQuery: "keyset"},
+					Limit:      2,
+					Sort:       sort,
+					After:      after,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(page) == 0 {
+					break
+				}
+				for _, user := range page {
+					names = append(names, user.Username)
+				}
+				last := page[len(page)-1]
+				after = &Cursor{CreatedAt: last.CreatedAt, Username: last.Username, ID: last.ID}
+			}
+
+			// Страницы после курсора стыкуются без повторов и пропусков
+			want := []string{"keyset_0", "keyset_1", "keyset_2", "keyset_3", "keyset_4"}
+			if sort.Desc {
+				slices.Reverse(want)
+			}
+			if !slices.Equal(names, want) {
+				t.Fatalf("сортировка %+v: получено %v, ожидалось %v", sort, names, want)
+			}
+		}
+	})
+
+	t.Run("удаление", func(t *testing.T) {
+		user := createTestUser(t, repo, "deleted", "deleted@example.com")
+
+		if err := repo.Delete(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetByID удалённого вернул %v, ожидался ErrNotFound", err)
+		}
+		// Запись остаётся в хранилище и видна только с IncludeDeleted
+		for includeDeleted, want := range map[bool]int64{false: 0, true: 1} {
+			count, err := repo.Count(ctx, ListFilter{IncludeDeleted: includeDeleted, UsernameQuery: "deleted"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != want {
+				t.Fatalf("IncludeDeleted=%v: найдено %d, ожидалось %d", includeDeleted, count, want)
+			}
+		}
+
+		// Ни одна строка не затронута: и повторное удаление, и неизвестный идентификатор
+		if err := repo.Delete(ctx, user.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("повторный Delete вернул %v, ожидался ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, uuid.New()); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Delete неизвестного вернул %v, ожидался ErrNotFound", err)
+		}
+	})
+
+	t.Run("обновление", func(t *testing.T) {
+		user := createTestUser(t, repo, "updated", "updated@example.com")
+		other := createTestUser(t, repo, "other", "other@example.com")
+
+		createdAt, updatedAt := user.CreatedAt, user.UpdatedAt
+		time.Sleep(10 * time.Millisecond)
+		user.Username, user.Email, user.PasswordHash = "renamed", "renamed@example.com", ""
+		if err := repo.Update(ctx, &user); err != nil {
+			t.Fatal(err)
+		}
+		// updated_at сдвигается при каждом обновлении, created_at остаётся прежним
+		if !user.UpdatedAt.After(updatedAt) || !user.CreatedAt.Equal(createdAt) {
+			t.Fatalf("после Update created_at=%v (было %v), updated_at=%v (было %v)",
+				user.CreatedAt, createdAt, user.UpdatedAt, updatedAt)
+		}
+		got, err := repo.GetByID(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Username != "renamed" || got.Email != "renamed@example.com" {
+			t.Fatalf("после Update получен %+v", got)
+		}
+
+		user.Email = other.Email
+		if err := repo.Update(ctx, &user); !errors.Is(err, ErrEmailTaken) {
+			t.Fatalf("Update на занятый email вернул %v, ожидался ErrEmailTaken", err)
+		}
+
+		missing := model.User{ID: uuid.New(), Username: "missing", Email: "missing@example.com"}
+		if err := repo.Update(ctx, &missing); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Update неизвестного вернул %v, ожидался ErrNotFound", err)
+		}
+	})
+
+	t.Run("частичное обновление", func(t *testing.T) {
+		user := createTestUser(t, repo, "patched", "patched@example.com")
+		other := createTestUser(t, repo, "patch_other", "patch_other@example.com")
+
+		// Меняется только переданное поле
+		email := "patched2@example.com"
+		got, err := repo.Patch(ctx, user.ID, UserPatch{Email: &email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Username != "patched" || got.Email != email {
+			t.Fatalf("после Patch email получен %+v", got)
+		}
+		username := "patched2"
+		got, err = repo.Patch(ctx, user.ID, UserPatch{Username: &username})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Username != username || got.Email != email {
+			t.Fatalf("после Patch имени получен %+v", got)
+		}
+
+		if _, err := repo.Patch(ctx, user.ID, UserPatch{Username: &other.Username}); !errors.Is(err, ErrUsernameTaken) {
+			t.Fatalf("Patch на занятое имя вернул %v, ожидался ErrUsernameTaken", err)
+		}
+		if _, err := repo.Patch(ctx, uuid.New(), UserPatch{Email: &email}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Patch неизвестного вернул %v, ожидался ErrNotFound", err)
+		}
+	})
+
+	t.Run("удаление по фильтру", func(t *testing.T) {
+		first := createTestUser(t, repo, "purge_a", "purge_a@example.com")
+		second := createTestUser(t, repo, "purge_b", "purge_b@example.com")
+		keeper := createTestUser(t, repo, "keeper", "purge@example.org")
+
+		ids, err := repo.DeleteMatching(ctx, ListFilter{UsernameQuery: "purge"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != 2 || !slices.Contains(ids, first.ID) || !slices.Contains(ids, second.ID) {
+			t.Fatalf("удалены %v, ожидались %s и %s", ids, first.ID, second.ID)
+		}
+		// Под фильтр не попал: email содержит purge, но поиск идёт по имени
+		if _, err := repo.GetByID(ctx, keeper.ID); err != nil {
+			t.Fatalf("пользователь вне фильтра: %v", err)
+		}
+		// Удалённые остаются в хранилище и повторно не удаляются
+		if count, err := repo.Count(ctx, ListFilter{IncludeDeleted: true, UsernameQuery: "purge"}); err != nil || count != 2 {
+			t.Fatalf("с IncludeDeleted найдено %d, %v; ожидалось 2", count, err)
+		}
+		if ids, err := repo.DeleteMatching(ctx, ListFilter{UsernameQuery: "purge", IncludeDeleted: true}); err != nil || len(ids) != 0 {
+			t.Fatalf("повторное удаление вернуло %v, %v", ids, err)
+		}
+
+		// Фильтр по email — точное совпадение
+		ids, err = repo.DeleteMatching(ctx, ListFilter{Email: keeper.Email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(ids, []uuid.UUID{keeper.ID}) {
+			t.Fatalf("по email удалены %v, ожидался %s", ids, keeper.ID)
+		}
+	})
+
+	t.Run("восстановление", func(t *testing.T) {
+		user := createTestUser(t, repo, "restored", "restored@example.com")
+
+		if _, err := repo.Restore(ctx, user.ID); !errors.Is(err, ErrNotDeleted) {
+			t.Fatalf("Restore активного вернул %v, ожидался ErrNotDeleted", err)
+		}
+		if err := repo.Delete(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.Restore(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.DeletedAt != nil || got.Email != user.Email {
+			t.Fatalf("после Restore получен %+v", got)
+		}
+		if _, err := repo.GetByID(ctx, user.ID); err != nil {
+			t.Fatalf("GetByID восстановленного: %v", err)
+		}
+
+		// Email удалённого пользователя занят другим активным: восстановление отклоняется
+		if err := repo.Delete(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		createTestUser(t, repo, "restored_new", user.Email)
+		if _, err := repo.Restore(ctx, user.ID); !errors.Is(err, ErrEmailTaken) {
+			t.Fatalf("Restore с занятым email вернул %v, ожидался ErrEmailTaken", err)
+		}
+
+		if _, err := repo.Restore(ctx, uuid.New()); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Restore неизвестного вернул %v, ожидался ErrNotFound", err)
+		}
+	})
+}
+
+func TestEscapeLike(t *testing.T) {
+	for value, want := range map[string]string{
+		"alice":   "alice",
+		"50%":     `50\%`,
+		"a_b":     `a\_b`,
+		`back\sl`: `back\\sl`,
+		`%_\`:     `\%\_\\`,
+	} {
+		if got := escapeLike(value); got != want {
+			t.Errorf("escapeLike(%q) = %q, ожидалось %q", value, got, want)
+		}
+	}
+}
+
+func TestPostgresUsernameCheck(t *testing.T) {
+	repo := NewPostgresUserRepository(testPool(t), RetryPolicy{})
+
+	// CHECK в базе повторяет правила API и не пропускает запись в обход проверок обработчика
+	for _, username := range []string{"ab", "bad name", "has@sign", "a123456789012345678901234567890123"} {
+		user := model.User{Username: username, Email: "check@example.com", PasswordHash: "hash"}
+		if err := repo.Create(context.Background(), &user); err == nil {
+			t.Fatalf("база сохранила некорректное имя %q", username)
+		}
+	}
+}
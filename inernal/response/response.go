@@ -0,0 +1,98 @@
+package response
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// Машиночитаемые коды ошибок API. Значения стабильны, клиенты могут на них опираться
+const (
+	CodeInvalidJSON           = "invalid_json"
+	CodeValidationFailed      = "validation_failed"
+	CodeInvalidID             = "invalid_id"
+	CodeInvalidQuery          = "invalid_query"
+	CodeNotFound              = "not_found"
+	CodeEmailTaken            = "email_taken"
+	CodeUsernameTaken         = "username_taken"
+	CodeNotDeleted            = "not_deleted"
+	CodeDisposableEmail       = "disposable_email"
+	CodeIdempotencyReuse      = "idempotency_key_reused"
+	CodeIdempotencyInProgress = "idempotency_request_in_progress"
+	CodeUnauthorized          = "unauthorized"
+	CodeForbidden             = "forbidden"
+	CodeMethodNotAllowed      = "method_not_allowed"
+	CodePayloadTooLarge       = "payload_too_large"
+	CodeUnsupportedMedia      = "unsupported_media_type"
+	CodeForbiddenOrigin       = "forbidden_origin"
+	CodeRateLimited           = "rate_limited"
+	CodeUnavailable           = "unavailable"
+	CodeTimeout               = "request_timeout"
+	CodeInternal              = "internal_error"
+)
+
+// envelope — тело успешного ответа: {"data":...,"meta":{...}}. В meta попадают сведения о самом ответе,
+// например параметры пагинации; у одиночных объектов её нет
+type envelope struct {
+	Data any `json:"data"`
+	Meta any `json:"meta,omitempty"`
+}
+
+// errorBody — тело ответа с ошибкой: {"error":{"code":...,"message":...}}
+type errorBody struct {
+	Error errorDetails `json:"error"`
+}
+
+// errorDetails — машиночитаемый код и человекочитаемое описание ошибки
+type errorDetails struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Details — необязательные подробности, например ошибки отдельных элементов пакета
+	Details any `json:"details,omitempty"`
+}
+
+// JSON сериализует значение в JSON и отправляет его клиенту с указанным статусом
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Ошибка записи ответа", "error", err)
+	}
+}
+
+// Data отправляет успешный ответ в едином формате: данные в data, необязательные метаданные в meta
+func Data(w http.ResponseWriter, status int, data, meta any) {
+	JSON(w, status, envelope{Data: data, Meta: meta})
+}
+
+// DataHead отвечает на HEAD теми же статусом и заголовками, что Data на GET, включая Content-Length,
+// но без тела. Тело не буферизуется и не отправляется: считается только его длина
+func DataHead(w http.ResponseWriter, status int, data, meta any) {
+	var length byteCounter
+	if err := json.NewEncoder(&length).Encode(envelope{Data: data, Meta: meta}); err != nil {
+		slog.Error("Ошибка сериализации ответа", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(int(length)))
+	w.WriteHeader(status)
+}
+
+// byteCounter — io.Writer, который только считает записанные байты
+type byteCounter int
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// Error отправляет клиенту ошибку в едином JSON-формате
+func Error(w http.ResponseWriter, status int, code, message string) {
+	JSON(w, status, errorBody{Error: errorDetails{Code: code, Message: message}})
+}
+
+// ErrorWithDetails отправляет ошибку в едином формате с дополнительным полем details
+func ErrorWithDetails(w http.ResponseWriter, status int, code, message string, details any) {
+	JSON(w, status, errorBody{Error: errorDetails{Code: code, Message: message, Details: details}})
+}
@@ -0,0 +1,54 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+)
+
+// samplePassword — общий пароль демонстрационных пользователей; годится только для локальной разработки
+const samplePassword = "demo-password"
+
+// sampleUsers — фиксированный набор пользователей для локальной разработки и демонстраций
+var sampleUsers = []model.User{
+	{Username: "alice", Email: "alice@example.com"},
+	{Username: "bob", Email: "bob@example.com"},
+	{Username: "carol", Email: "carol@example.com"},
+}
+
+// Seed добавляет демонстрационных пользователей и возвращает, сколько из них создано.
+// Повторный запуск безопасен: пользователи, чьи email или имя уже заняты, пропускаются.
+// Вызывается после миграций и только по явному запросу, потому что пароль у всех известен заранее
+func Seed(ctx context.Context, users repository.UserRepository, bcryptCost int) (int, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(samplePassword), bcryptCost)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось захешировать пароль: %w", err)
+	}
+
+	created := 0
+	for _, sample := range sampleUsers {
+		user := sample
+		user.PasswordHash = string(hash)
+
+		// Уникальные индексы сами отсекают уже существующих, в том числе при параллельном запуске
+		err := users.Create(ctx, &user)
+		if errors.Is(err, repository.ErrEmailTaken) || errors.Is(err, repository.ErrUsernameTaken) {
+			slog.Debug("Демонстрационный пользователь уже существует", "email", user.Email)
+			continue
+		}
+		if err != nil {
+			return created, fmt.Errorf("не удалось создать пользователя %s: %w", sample.Email, err)
+		}
+
+		slog.Info("Создан демонстрационный пользователь", "user_id", user.ID, "email", user.Email)
+		created++
+	}
+
+	return created, nil
+}
@@ -0,0 +1,71 @@
+package seed
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+	"github.com/olezhek28/docker-compose-tutorial/inernal/repository"
+)
+
+func TestMain(m *testing.M) {
+	// Seed логирует каждого созданного пользователя; в выводе тестов это только мешает
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+func TestSeedIdempotent(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+
+	created, err := Seed(ctx, users, bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != len(sampleUsers) {
+		t.Fatalf("создано %d, ожидалось %d", created, len(sampleUsers))
+	}
+
+	// Повторный запуск ничего не добавляет
+	created, err = Seed(ctx, users, bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 0 {
+		t.Fatalf("повторный запуск создал %d пользователей", created)
+	}
+	if count, err := users.Count(ctx, repository.ListFilter{}); err != nil || count != int64(len(sampleUsers)) {
+		t.Fatalf("в хранилище %d пользователей (err=%v), ожидалось %d", count, err, len(sampleUsers))
+	}
+}
+
+func TestSeedSkipsExisting(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+
+	// Пользователь с email из набора уже зарегистрирован под другим именем
+	existing := model.User{Username: "real_alice", Email: sampleUsers[0].Email, PasswordHash: "hash"}
+	if err := users.Create(ctx, &existing); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := Seed(ctx, users, bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != len(sampleUsers)-1 {
+		t.Fatalf("создано %d, ожидалось %d", created, len(sampleUsers)-1)
+	}
+	got, err := users.GetByID(ctx, existing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Username != "real_alice" || got.PasswordHash != "hash" {
+		t.Fatalf("существующий пользователь изменён: %+v", got)
+	}
+}
@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Идентификаторы из примера спецификации W3C Trace Context
+const (
+	upstreamTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	upstreamSpanID      = "00f067aa0ba902b7"
+	upstreamTraceparent = "00-" + upstreamTraceID + "-" + upstreamSpanID + "-01"
+)
+
+// recordSpans — включает запись спанов в память и формат traceparent до конца теста
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return recorder
+}
+
+// serveTraced — прогоняет запрос через Middleware и возвращает контекст, который увидел обработчик
+func serveTraced(t *testing.T, traceparent string, handle func(ctx context.Context)) context.Context {
+	t.Helper()
+
+	var seen context.Context
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Context()
+		if handle != nil {
+			handle(r.Context())
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	if traceparent != "" {
+		req.Header.Set("traceparent", traceparent)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	return seen
+}
+
+func TestMiddlewareContinuesIncomingTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx := serveTraced(t, upstreamTraceparent, nil)
+	if got := trace.SpanContextFromContext(ctx).TraceID().String(); got != upstreamTraceID {
+		t.Fatalf("trace_id в контексте %s, ожидался %s из traceparent", got, upstreamTraceID)
+	}
+
+	// Логи ссылаются на тот же трейс
+	attrs := LogAttrs(ctx)
+	if len(attrs) != 4 || attrs[0] != "trace_id" || attrs[1] != upstreamTraceID {
+		t.Fatalf("LogAttrs = %v", attrs)
+	}
+
+	// Спан сервера — дочерний для спана вызывающего сервиса
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Parent().SpanID().String() != upstreamSpanID {
+		t.Fatalf("записаны спаны %v, ожидался один с родителем %s", spans, upstreamSpanID)
+	}
+}
+
+func TestMiddlewareStartsNewTrace(t *testing.T) {
+	recordSpans(t)
+
+	// Без traceparent идентификаторы генерируются заново для каждого запроса
+	first := trace.SpanContextFromContext(serveTraced(t, "", nil))
+	second := trace.SpanContextFromContext(serveTraced(t, "", nil))
+	if !first.IsValid() || !second.IsValid() || first.TraceID() == second.TraceID() {
+		t.Fatalf("trace_id %s и %s: ожидались два разных новых", first.TraceID(), second.TraceID())
+	}
+
+	// Некорректный заголовок игнорируется так же, как отсутствующий
+	if got := trace.SpanContextFromContext(serveTraced(t, "00-garbage", nil)); !got.IsValid() {
+		t.Fatal("при некорректном traceparent трейс не начат")
+	}
+}
+
+func TestQueryTracerSpanInRequestTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	tracer := NewQueryTracer()
+
+	serveTraced(t, upstreamTraceparent, func(ctx context.Context) {
+		ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT id FROM users WHERE id = $1"})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	})
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("записано %d спанов, ожидались запрос к базе и HTTP-запрос", len(spans))
+	}
+	query, server := spans[0], spans[1]
+	if query.Name() != "SELECT" || query.SpanContext().TraceID().String() != upstreamTraceID ||
+		query.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatalf("спан запроса %q в трейсе %s с родителем %s", query.Name(), query.SpanContext().TraceID(), query.Parent().SpanID())
+	}
+}
@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+// EventUserCreated — тип события о создании пользователя
+const EventUserCreated = "user.created"
+
+// Options — настройки доставки вебхуков
+type Options struct {
+	// URL — адрес, на который отправляются события
+	URL string
+	// Attempts — максимальное число попыток доставки одного события
+	Attempts int
+	// Timeout — таймаут одной попытки
+	Timeout time.Duration
+	// BaseDelay — пауза перед второй попыткой; каждая следующая пауза вдвое длиннее
+	BaseDelay time.Duration
+}
+
+// event — тело запроса вебхука
+type event struct {
+	Event string     `json:"event"`
+	Data  model.User `json:"data"`
+}
+
+// Notifier — отправляет события о пользователях на внешний URL в фоне, не задерживая HTTP-ответ.
+// Неудачные попытки повторяются с экспоненциальной паузой; ошибки доставки только логируются
+type Notifier struct {
+	opts   Options
+	client *http.Client
+	wg     sync.WaitGroup
+	// ctx отменяется при остановке, чтобы прервать паузы между повторами
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewNotifier(opts Options) *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Notifier{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// UserCreated ставит в очередь доставку события о создании пользователя и сразу возвращается
+func (n *Notifier) UserCreated(user model.User) {
+	payload, err := json.Marshal(event{Event: EventUserCreated, Data: user})
+	if err != nil {
+		slog.Error("Не удалось сериализовать событие вебхука", "error", err)
+		return
+	}
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliver(EventUserCreated, user.ID.String(), payload)
+	}()
+}
+
+// Shutdown дожидается доставки уже поставленных событий, но не дольше, чем живёт ctx.
+// После этого оставшиеся повторы прерываются
+func (n *Notifier) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		n.cancel()
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		return fmt.Errorf("не все вебхуки доставлены до остановки: %w", ctx.Err())
+	}
+}
+
+// deliver отправляет событие, повторяя попытки при сетевых ошибках и ответах не из диапазона 2xx
+func (n *Notifier) deliver(eventType, userID string, payload []byte) {
+	logger := slog.With("event", eventType, "user_id", userID)
+	delay := n.opts.BaseDelay
+
+	for attempt := 1; attempt <= n.opts.Attempts; attempt++ {
+		err := n.send(payload)
+		if err == nil {
+			logger.Debug("Вебхук доставлен", "attempt", attempt)
+			return
+		}
+
+		if attempt == n.opts.Attempts {
+			logger.Error("Не удалось доставить вебхук", "attempts", attempt, "error", err)
+			return
+		}
+
+		logger.Warn("Ошибка доставки вебхука, повторяем", "attempt", attempt, "retry_in", delay.String(), "error", err)
+		select {
+		case <-time.After(delay):
+		case <-n.ctx.Done():
+			logger.Error("Доставка вебхука прервана остановкой сервиса", "attempt", attempt)
+			return
+		}
+		delay *= 2
+	}
+}
+
+// send выполняет одну попытку доставки
+func (n *Notifier) send(payload []byte) error {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, n.opts.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("не удалось создать запрос: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("получатель ответил %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/olezhek28/docker-compose-tutorial/inernal/model"
+)
+
+func TestMain(m *testing.M) {
+	// Повторы и неудачи доставки логируются; в выводе тестов это только мешает
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// receiver — тестовый получатель вебхуков: отвечает статусами из statuses по очереди
+// (последний повторяется) и запоминает тела запросов
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, body)
+	status := r.statuses[min(len(r.bodies), len(r.statuses))-1]
+	if req.Header.Get("Content-Type") != "application/json" {
+		status = http.StatusUnsupportedMediaType
+	}
+	w.WriteHeader(status)
+}
+
+// calls — сколько запросов получено
+func (r *receiver) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.bodies)
+}
+
+// testNotifier — отправитель на адрес тестового получателя с короткими паузами между повторами
+func testNotifier(t *testing.T, recv *receiver) *Notifier {
+	t.Helper()
+
+	server := httptest.NewServer(recv)
+	t.Cleanup(server.Close)
+
+	return NewNotifier(Options{URL: server.URL, Attempts: 3, Timeout: time.Second, BaseDelay: time.Millisecond})
+}
+
+// shutdown — дожидается доставки всех поставленных событий
+func shutdown(t *testing.T, n *Notifier) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifierDeliversPayload(t *testing.T) {
+	recv := &receiver{statuses: []int{http.StatusNoContent}}
+	n := testNotifier(t, recv)
+
+	user := model.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com", PasswordHash: "hash"}
+	n.UserCreated(user)
+	shutdown(t, n)
+
+	if recv.calls() != 1 {
+		t.Fatalf("получено %d запросов, ожидался один", recv.calls())
+	}
+	var got struct {
+		Event string         `json:"event"`
+		Data  map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(recv.bodies[0], &got); err != nil {
+		t.Fatalf("тело вебхука не JSON: %v", err)
+	}
+	if got.Event != EventUserCreated || got.Data["id"] != user.ID.String() || got.Data["email"] != "alice@example.com" {
+		t.Fatalf("доставлено %s", recv.bodies[0])
+	}
+	// Хеш пароля наружу не уходит
+	if _, ok := got.Data["password_hash"]; ok {
+		t.Fatalf("в вебхук попал хеш пароля: %s", recv.bodies[0])
+	}
+}
+
+func TestNotifierRetries(t *testing.T) {
+	// Две ошибки подряд, затем успех: третья попытка доставляет событие
+	recv := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK}}
+	n := testNotifier(t, recv)
+	n.UserCreated(model.User{ID: uuid.New()})
+	shutdown(t, n)
+	if recv.calls() != 3 {
+		t.Fatalf("получено %d запросов, ожидалось 3", recv.calls())
+	}
+
+	// Получатель всё время отвечает ошибкой: попыток не больше Attempts
+	recv = &receiver{statuses: []int{http.StatusServiceUnavailable}}
+	n = testNotifier(t, recv)
+	n.UserCreated(model.User{ID: uuid.New()})
+	shutdown(t, n)
+	if recv.calls() != 3 {
+		t.Fatalf("получено %d запросов, ожидалось ровно 3 попытки", recv.calls())
+	}
+}
+
+func TestNotifierDoesNotBlock(t *testing.T) {
+	// Получатель отвечает только после сигнала: UserCreated не ждёт доставки
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	n := NewNotifier(Options{URL: server.URL, Attempts: 1, Timeout: 5 * time.Second})
+
+	done := make(chan struct{})
+	go func() {
+		n.UserCreated(model.User{ID: uuid.New()})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("UserCreated ждёт ответа получателя")
+	}
+
+	close(release)
+	shutdown(t, n)
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+-- запрещаем регистрировать несколько пользователей с одинаковым email
+ALTER TABLE users
+    ADD CONSTRAINT users_email_key UNIQUE (email);
+
+-- +goose Down
+-- снимаем ограничение уникальности email
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_email_key;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- добавляем время последнего изменения пользователя; для существующих записей берём время создания
+ALTER TABLE users
+    ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE users
+SET updated_at = created_at
+WHERE created_at IS NOT NULL;
+
+-- индекс для сортировки списка пользователей по времени регистрации
+CREATE INDEX users_created_at_idx ON users (created_at);
+
+-- +goose Down
+-- удаляем индекс и колонку времени изменения
+DROP INDEX IF EXISTS users_created_at_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS updated_at;
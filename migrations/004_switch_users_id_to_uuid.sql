@@ -0,0 +1,31 @@
+-- +goose Up
+-- gen_random_uuid() входит в pgcrypto (в Postgres 13+ доступна и без расширения)
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- новая колонка с недетерминированным значением по умолчанию: каждая существующая строка
+-- получает свой UUID прямо при добавлении колонки
+ALTER TABLE users
+    ADD COLUMN uuid UUID NOT NULL DEFAULT gen_random_uuid();
+
+-- заменяем целочисленный первичный ключ на UUID
+ALTER TABLE users
+    DROP CONSTRAINT users_pkey;
+ALTER TABLE users
+    DROP COLUMN id;
+ALTER TABLE users
+    RENAME COLUMN uuid TO id;
+ALTER TABLE users
+    ADD PRIMARY KEY (id);
+
+-- +goose Down
+-- возвращаем целочисленный ключ; SERIAL пронумерует существующие строки заново
+ALTER TABLE users
+    DROP CONSTRAINT users_pkey;
+ALTER TABLE users
+    RENAME COLUMN id TO uuid;
+ALTER TABLE users
+    ADD COLUMN id SERIAL;
+ALTER TABLE users
+    ADD PRIMARY KEY (id);
+ALTER TABLE users
+    DROP COLUMN uuid;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- время мягкого удаления: NULL означает, что пользователь активен
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMP NULL;
+
+-- email должен быть уникален только среди активных пользователей, иначе удалённая запись
+-- навсегда заблокирует свой адрес. Имя сохраняем прежним, на него опирается обработка конфликтов
+ALTER TABLE users
+    DROP CONSTRAINT users_email_key;
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE deleted_at IS NULL;
+
+-- +goose Down
+-- возвращаем безусловную уникальность email; мягко удалённые записи при этом удаляются окончательно
+DELETE FROM users
+WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS users_email_key;
+ALTER TABLE users
+    ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deleted_at;
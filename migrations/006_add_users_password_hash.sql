@@ -0,0 +1,8 @@
+-- +goose Up
+-- bcrypt-хеш пароля; у пользователей, созданных до появления паролей, он пустой
+ALTER TABLE users
+    ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN IF EXISTS password_hash;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- те же правила, что проверяет приложение: 3–32 символа, латинские буквы, цифры, _ и -.
+-- NOT VALID не проверяет уже существующие записи, но применяется ко всем новым вставкам и обновлениям
+ALTER TABLE users
+    ADD CONSTRAINT users_username_check CHECK (username ~ '^[A-Za-z0-9_-]{3,32}$') NOT VALID;
+
+-- +goose Down
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_username_check;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- имя пользователя уникально среди активных пользователей, так же как email.
+-- Имя индекса используется приложением, чтобы понять, какое поле вызвало конфликт
+CREATE UNIQUE INDEX users_username_key ON users (username) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS users_username_key;
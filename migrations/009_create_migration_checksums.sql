@@ -0,0 +1,11 @@
+-- +goose Up
+-- контрольные суммы применённых миграций: мигратор сверяет их с файлами и не даёт
+-- применять миграции поверх отредактированных задним числом
+CREATE TABLE migration_checksums (
+    version_id BIGINT PRIMARY KEY,
+    checksum   TEXT      NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS migration_checksums;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- сохранённые ответы на запросы с заголовком Idempotency-Key: повторный запрос с тем же ключом
+-- получает исходный ответ, а не создаёт пользователя ещё раз
+CREATE TABLE idempotency_keys (
+    key          TEXT PRIMARY KEY,
+    request_hash TEXT      NOT NULL,
+    status_code  INT       NOT NULL,
+    headers      JSONB     NOT NULL DEFAULT '{}',
+    body         BYTEA     NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at   TIMESTAMP NOT NULL
+);
+
+-- индекс для быстрой очистки просроченных ключей
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS idempotency_keys;
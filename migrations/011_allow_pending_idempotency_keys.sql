@@ -0,0 +1,15 @@
+-- +goose Up
+-- ключ бронируется до выполнения запроса: строка без status_code и body означает, что запрос
+-- с этим ключом ещё выполняется, и параллельный повтор не создаст пользователя второй раз
+ALTER TABLE idempotency_keys
+    ALTER COLUMN status_code DROP NOT NULL,
+    ALTER COLUMN body DROP NOT NULL;
+
+-- +goose Down
+-- незавершённые брони восстановить как ответы нельзя, поэтому удаляем их
+DELETE FROM idempotency_keys
+WHERE status_code IS NULL;
+
+ALTER TABLE idempotency_keys
+    ALTER COLUMN status_code SET NOT NULL,
+    ALTER COLUMN body SET NOT NULL;
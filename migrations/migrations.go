@@ -0,0 +1,9 @@
+// Package migrations встраивает SQL-миграции в бинарник, чтобы их можно было применить без директории на диске
+package migrations
+
+import "embed"
+
+// FS — все SQL-миграции этой директории
+//
+//go:embed *.sql
+var FS embed.FS